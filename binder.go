@@ -0,0 +1,469 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "math"
+import "reflect"
+import "sort"
+import "strings"
+import "time"
+
+var reflect_time reflect.Type = reflect.TypeOf(time.Time{})
+
+// Return the Type derived from the Go type rt. Integers are int64,
+// floats are float64, slices and arrays are list<T>, maps are map<K, V>
+// and structs (or pointers to struct) are the Go struct type.
+func Reflect_type(rt reflect.Type)(Type, error) {
+	var elem Type
+	var key Type
+	var err error
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return Type_of[bool](), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+	     reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Type_of[int64](), nil
+	case reflect.Float32, reflect.Float64:
+		return Type_of[float64](), nil
+	case reflect.String:
+		return Type_of[string](), nil
+	case reflect.Slice, reflect.Array:
+		elem, err = Reflect_type(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return List_type(elem), nil
+	case reflect.Map:
+		key, err = Reflect_type(rt.Key())
+		if err != nil {
+			return nil, err
+		}
+		elem, err = Reflect_type(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Map_type(key, elem), nil
+	case reflect.Ptr:
+		if rt.Elem().Kind() == reflect.Struct {
+			return Reflect_type(rt.Elem())
+		}
+	case reflect.Struct:
+		return Go_type_of(rt), nil
+	}
+	return nil, fmt.Errorf("Unsupported Go type %s", rt.String())
+}
+
+// Value of a Go struct. Its type is the Go struct type.
+type Struct_value struct {
+	rv reflect.Value
+}
+
+func (s *Struct_value)Descr()(string) {
+	return fmt.Sprintf("%+v", s.rv.Interface())
+}
+
+func (s *Struct_value)Type()(Type) {
+	return Go_type_of(s.rv.Type())
+}
+
+// Return the Go struct
+func (s *Struct_value)Interface()(interface{}) {
+	return s.rv.Interface()
+}
+
+/* sort the keys of a Go map, the Go map order is random */
+func sort_map_keys(keys []reflect.Value) {
+	sort.Slice(keys, func(i int, j int)(bool) {
+		var a reflect.Value
+		var b reflect.Value
+
+		a = keys[i]
+		b = keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		case reflect.Bool:
+			return !a.Bool() && b.Bool()
+		}
+		return fmt.Sprintf("%+v", a.Interface()) < fmt.Sprintf("%+v", b.Interface())
+	})
+}
+
+/* convert Go value to Value */
+func reflect_value(rv reflect.Value)(Value, error) {
+	var t Type
+	var elem Type
+	var l *List_value
+	var m *Map_value
+	var v Value
+	var k Value
+	var keys []reflect.Value
+	var key reflect.Value
+	var i int
+	var err error
+
+	if rv.Type() == reflect_time {
+		return Value_of(rv.Interface().(time.Time)), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Value_of(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Value_of(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("Value %d of %s overflows int64", rv.Uint(), rv.Type().String())
+		}
+		return Value_of(int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return Value_of(rv.Float()), nil
+	case reflect.String:
+		return Value_of(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		elem, err = Reflect_type(rv.Type().Elem())
+		if err != nil {
+			return nil, err
+		}
+		l = New_list_value(elem, nil)
+		for i = 0; i < rv.Len(); i++ {
+			v, err = reflect_value(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			l.Items = append(l.Items, v)
+		}
+		return l, nil
+	case reflect.Map:
+		t, err = Reflect_type(rv.Type())
+		if err != nil {
+			return nil, err
+		}
+		m = New_map_value(t.(*Param_type).args[0], t.(*Param_type).args[1])
+		keys = rv.MapKeys()
+		sort_map_keys(keys)
+		for _, key = range keys {
+			k, err = reflect_value(key)
+			if err != nil {
+				return nil, err
+			}
+			v, err = reflect_value(rv.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			m.Keys = append(m.Keys, k)
+			m.Values = append(m.Values, v)
+		}
+		return m, nil
+	case reflect.Ptr:
+		if rv.Type().Elem().Kind() == reflect.Struct {
+			if rv.IsNil() {
+				return nil, fmt.Errorf("Nil pointer to %s", rv.Type().Elem().String())
+			}
+			return reflect_value(rv.Elem())
+		}
+	case reflect.Struct:
+		return &Struct_value{rv: rv}, nil
+	}
+	return nil, fmt.Errorf("Unsupported Go type %s", rv.Type().String())
+}
+
+// Return the Value representing the Go value v. The type of the
+// returned value is Reflect_type(reflect.TypeOf(v)).
+func Reflect_value(v interface{})(Value, error) {
+	if v == nil {
+		return nil, fmt.Errorf("Unsupported nil value")
+	}
+	return reflect_value(reflect.ValueOf(v))
+}
+
+/* return the struct type of rt, following pointer */
+func struct_type(rt reflect.Type)(reflect.Type, bool) {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt, rt.Kind() == reflect.Struct && rt != reflect_time
+}
+
+/* find field named name in the struct. the name is the value of the
+ * tag "expr" or the Go field name. The tag "-" hides the field.
+ */
+func struct_field(rt reflect.Type, name string)(reflect.StructField, bool) {
+	var f reflect.StructField
+	var tag string
+	var i int
+
+	for i = 0; i < rt.NumField(); i++ {
+		f = rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag = strings.Split(f.Tag.Get("expr"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return f, true
+		}
+	}
+	return f, false
+}
+
+/* path of fields from a struct type */
+type field_path struct {
+	index [][]int
+	typ reflect.Type
+}
+
+/* resolve dotted path from the struct type rt */
+func resolve_path(rt reflect.Type, path string)(*field_path, error) {
+	var fp *field_path
+	var name string
+	var f reflect.StructField
+	var st reflect.Type
+	var ok bool
+
+	fp = &field_path{typ: rt}
+	for _, name = range strings.Split(path, ".") {
+		st, ok = struct_type(fp.typ)
+		if !ok {
+			return nil, fmt.Errorf("Can't access %q, %s is not a struct", name, fp.typ.String())
+		}
+		f, ok = struct_field(st, name)
+		if !ok {
+			return nil, fmt.Errorf("Struct %s has no field %q", st.String(), name)
+		}
+		fp.index = append(fp.index, f.Index)
+		fp.typ = f.Type
+	}
+	return fp, nil
+}
+
+/* get value of the path from the struct rv */
+func (fp *field_path)get(rv reflect.Value)(Value, error) {
+	var index []int
+
+	for _, index = range fp.index {
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil, fmt.Errorf("Nil pointer to %s", rv.Type().Elem().String())
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.FieldByIndex(index)
+	}
+	return reflect_value(rv)
+}
+
+// Binder makes the fields of a Go struct addressable in expressions, as
+// variables reading the struct stored in the context, or with member
+// access operators consuming a struct value. The fields are named by the
+// tag `expr:"name"` or by their Go name, nested fields are designated by
+// dotted path like "address.city".
+type Binder struct {
+	root reflect.Type
+}
+
+// Create binder for the struct type of sample. sample is a struct or
+// a pointer to struct.
+func New_binder(sample interface{})(*Binder, error) {
+	var rt reflect.Type
+	var ok bool
+
+	if sample == nil {
+		return nil, fmt.Errorf("Binder needs a struct")
+	}
+	rt, ok = struct_type(reflect.TypeOf(sample))
+	if !ok {
+		return nil, fmt.Errorf("Binder needs a struct, got %s", reflect.TypeOf(sample).String())
+	}
+	return &Binder{root: rt}, nil
+}
+
+// Return the type of the bound struct
+func (b *Binder)Type()(Type) {
+	return Go_type_of(b.root)
+}
+
+/* key used to store the bound struct in the context */
+type binder_key struct {
+	b *Binder
+}
+
+// Return a context which store the struct read by the variables of the binder
+func (b *Binder)With(ctx context.Context, root interface{})(context.Context) {
+	return context.WithValue(ctx, binder_key{b: b}, root)
+}
+
+/* the variables of the same binder and path read the same field */
+type binder_var_key struct {
+	b *Binder
+	path string
+}
+
+/* element reading field of the struct stored in the context */
+type binder_var struct {
+	b *Binder
+	path string
+	fp *field_path
+	output_types [][]Type
+}
+
+func (v *binder_var)Precedence()(int) {
+	return 0
+}
+func (v *binder_var)Associativity()(int) {
+	return 0
+}
+func (v *binder_var)Kind()(int) {
+	return Kind_value
+}
+func (v *binder_var)String()(string) {
+	return v.path
+}
+func (v *binder_var)Input_types()([][]Type) {
+	return [][]Type{}
+}
+func (v *binder_var)Output_types()([][]Type) {
+	return v.output_types
+}
+func (v *binder_var)Variable()(interface{}) {
+	return binder_var_key{b: v.b, path: v.path}
+}
+func (v *binder_var)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var root interface{}
+	var st reflect.Type
+	var val Value
+	var err error
+
+	root = ctx.Value(binder_key{b: v.b})
+	if root == nil {
+		return nil, fmt.Errorf("Variable %q needs a struct %s bound in the context",
+		                       v.path, v.b.root.String())
+	}
+	st, _ = struct_type(reflect.TypeOf(root))
+	if st != v.b.root {
+		return nil, fmt.Errorf("Variable %q needs a struct %s bound in the context, got %s",
+		                       v.path, v.b.root.String(), reflect.TypeOf(root).String())
+	}
+	val, err = v.fp.get(reflect.ValueOf(root))
+	if err != nil {
+		return nil, err
+	}
+	return []Value{val}, nil
+}
+
+// Return value element which reads the field designated by path in the
+// struct stored in the context by With.
+func (b *Binder)Var(path string)(Elt, error) {
+	var fp *field_path
+	var t Type
+	var err error
+
+	fp, err = resolve_path(b.root, path)
+	if err != nil {
+		return nil, err
+	}
+	t, err = Reflect_type(fp.typ)
+	if err != nil {
+		return nil, err
+	}
+	return &binder_var{
+		b: b,
+		path: path,
+		fp: fp,
+		output_types: [][]Type{[]Type{t}},
+	}, nil
+}
+
+/* postfix operator which reads field of the struct value */
+type member_elt struct {
+	path string
+	fp *field_path
+	input_types [][]Type
+	output_types [][]Type
+}
+
+// Precedence of the member access operators, higher than all usual operators
+const Precedence_member = 1000
+
+func (m *member_elt)Precedence()(int) {
+	return Precedence_member
+}
+func (m *member_elt)Associativity()(int) {
+	return Associativity_left
+}
+func (m *member_elt)Kind()(int) {
+	return Kind_operator
+}
+func (m *member_elt)String()(string) {
+	return "." + m.path
+}
+func (m *member_elt)Input_types()([][]Type) {
+	return m.input_types
+}
+func (m *member_elt)Output_types()([][]Type) {
+	return m.output_types
+}
+func (m *member_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var s *Struct_value
+	var val Value
+	var ok bool
+	var err error
+
+	s, ok = in[0].(*Struct_value)
+	if !ok {
+		return nil, fmt.Errorf("%q needs struct value, got %s", m.String(), Type_string(in[0].Type()))
+	}
+	val, err = m.fp.get(s.rv)
+	if err != nil {
+		return nil, err
+	}
+	return []Value{val}, nil
+}
+
+// Return postfix operator which consumes a value of the struct type rt
+// and returns the field designated by path. The operator precedence is
+// Precedence_member, so "user .name + 1" reads the field before the
+// addition.
+func Member(rt reflect.Type, path string)(Elt, error) {
+	var fp *field_path
+	var st reflect.Type
+	var t Type
+	var ok bool
+	var err error
+
+	st, ok = struct_type(rt)
+	if !ok {
+		return nil, fmt.Errorf("Member access needs a struct, got %s", rt.String())
+	}
+	fp, err = resolve_path(st, path)
+	if err != nil {
+		return nil, err
+	}
+	t, err = Reflect_type(fp.typ)
+	if err != nil {
+		return nil, err
+	}
+	return &member_elt{
+		path: path,
+		fp: fp,
+		input_types: [][]Type{[]Type{Go_type_of(st)}},
+		output_types: [][]Type{[]Type{t}},
+	}, nil
+}
+
+// Return member access operator on the struct of the binder
+func (b *Binder)Member(path string)(Elt, error) {
+	return Member(b.root, path)
+}
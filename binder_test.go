@@ -0,0 +1,256 @@
+package shuntingyard
+
+import "context"
+import "io"
+import "math"
+import "reflect"
+import "strings"
+import "testing"
+import "time"
+
+type test_address struct {
+	City string `expr:"city"`
+	Zip uint32
+}
+
+type test_user struct {
+	Name string `expr:"name"`
+	Age int `expr:"age"`
+	Score float32
+	Admin bool `expr:"admin"`
+	Created time.Time `expr:"created"`
+	Tags []string `expr:"tags"`
+	Limits map[string]int `expr:"limits"`
+	Address *test_address `expr:"address"`
+	Secret string `expr:"-"`
+	hidden int
+}
+
+func Test_binder(t *testing.T) {
+	var b *Binder
+	var user *test_user
+	var e *Expr
+	var elt Elt
+	var v []Value
+	var val Value
+	var err error
+	var ctx context.Context
+	var name string
+	var typ Type
+	var m *Map_value
+	var i int
+	var ok bool
+	var c struct {
+		v interface{}
+		name string
+	}
+
+	user = &test_user{
+		Name: "alice",
+		Age: 42,
+		Score: 1.5,
+		Admin: true,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags: []string{"a", "b"},
+		Limits: map[string]int{"cpu": 4},
+		Address: &test_address{City: "Paris", Zip: 75001},
+		hidden: 1,
+	}
+
+	/* derived types */
+	for _, c = range []struct {
+		v interface{}
+		name string
+	}{
+		{true, "bool"},
+		{int8(1), "int64"},
+		{uint(1), "int64"},
+		{float32(1), "float64"},
+		{"a", "string"},
+		{time.Time{}, "time.Time"},
+		{[]int{}, "list<int64>"},
+		{[2]bool{}, "list<bool>"},
+		{map[string][]float64{}, "map<string, list<float64>>"},
+		{user, "shuntingyard.test_user"},
+	} {
+		typ, err = Reflect_type(reflect.TypeOf(c.v))
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+			continue
+		}
+		if typ.Name() != c.name {
+			t.Errorf("Expect %q, got %q", c.name, typ.Name())
+		}
+		val, err = Reflect_value(c.v)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+			continue
+		}
+		if !Type_equal(val.Type(), typ) {
+			t.Errorf("Expect value of type %q, got %q", typ.Name(), val.Type().Name())
+		}
+	}
+	_, err = Reflect_type(reflect.TypeOf(make(chan int)))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = Reflect_value(nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	_, err = New_binder(42)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	b, err = New_binder(user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, name = range []string{"Secret", "hidden", "unknown", "name.first", "address.street"} {
+		_, err = b.Var(name)
+		if err == nil {
+			t.Errorf("Expect error for %q, got no error", name)
+		}
+	}
+
+	/* variables */
+	ctx = b.With(context.Background(), user)
+	e = New(nil)
+	elt, _ = b.Var("address.city")
+	e.Push(elt)
+	elt, _ = b.Var("age")
+	e.Push(elt)
+	elt, _ = b.Var("tags")
+	e.Push(elt)
+	elt, _ = b.Var("limits")
+	e.Push(elt)
+	elt, _ = b.Var("created")
+	e.Push(elt)
+	elt, _ = b.Var("address.Zip")
+	e.Push(elt)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if Type_list(e.Output_types()) != "string, int64, list<string>, map<string, int64>, time.Time, int64" {
+		t.Errorf("Unexpected output types %q", Type_list(e.Output_types()))
+	}
+	v, err = e.Execute(With_checked(ctx), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if values_desc(v) != "(Paris:string, 42:int64, [a, b]:list<string>, {cpu: 4}:map<string, int64>, " +
+	                     "2020-01-02 03:04:05 +0000 UTC:time.Time, 75001:int64)" {
+		t.Errorf("Unexpected values %s", values_desc(v))
+	}
+	_, err = e.Execute(context.Background(), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* member access on struct value given as input */
+	e = New([][]Type{[]Type{b.Type()}})
+	elt, err = b.Member("address.city")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	e.Append(elt)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	val, _ = Reflect_value(user)
+	v, err = e.Execute(With_checked(context.Background()), []Value{val})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	name, err = Get[string](v[0])
+	if err != nil || name != "Paris" {
+		t.Errorf("Expect \"Paris\", got %q", name)
+	}
+
+	/* member access combined with other operators */
+	e = New(nil)
+	elt, _ = b.Var("address")
+	e.Append(elt)
+	elt, _ = Member(reflect.TypeOf(test_address{}), "Zip")
+	e.Append(elt)
+	e.Append(Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil }))
+	elt, _ = b.Var("age")
+	e.Append(elt)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	verif(t, e, "address|.Zip|age|+|")
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if v[0].Descr() != "75043" {
+		t.Errorf("Expect 75043, got %s", v[0].Descr())
+	}
+
+	/* nil nested struct */
+	user.Address = nil
+	_, err = e.Execute(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "Nil pointer") {
+		t.Errorf("Expect nil pointer error, got %v", err)
+	}
+
+	_, err = Member(reflect.TypeOf(1), "x")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* the context holds a struct of another type */
+	ctx = b.With(context.Background(), &test_address{City: "Paris"})
+	_, err = e.Execute(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "needs a struct") {
+		t.Errorf("Expect struct type error, got %v", err)
+	}
+
+	/* unsigned integers out of the int64 range */
+	val, err = Reflect_value(uint64(math.MaxInt64))
+	if err != nil || val.Descr() != "9223372036854775807" {
+		t.Errorf("Expect 9223372036854775807, got %v, %v", val, err)
+	}
+	_, err = Reflect_value(uint64(math.MaxInt64) + 1)
+	if err == nil {
+		t.Errorf("Expect overflow error, got no error")
+	}
+
+	/* the map keys are sorted */
+	for i = 0; i < 10; i++ {
+		val, _ = Reflect_value(map[int]string{3: "c", 1: "a", 2: "b", 4: "d"})
+		if val.Descr() != "{1: a, 2: b, 3: c, 4: d}" {
+			t.Fatalf("Expect {1: a, 2: b, 3: c, 4: d}, got %s", val.Descr())
+		}
+	}
+
+	/* the lookup matches the type of the key */
+	val, _ = Reflect_value(map[string]int{"1": 10})
+	m = val.(*Map_value)
+	val, ok = m.Lookup(Value_of("1"))
+	if !ok || val.Descr() != "10" {
+		t.Errorf("Expect 10 for key \"1\"")
+	}
+	_, ok = m.Lookup(Value_of(int64(1)))
+	if ok {
+		t.Errorf("Expect no value for key 1")
+	}
+
+	/* nil interface keys */
+	m = New_map_value(Type_of[error](), Type_of[int64]())
+	m.Keys = append(m.Keys, Value_of[error](nil))
+	m.Values = append(m.Values, Value_of(int64(1)))
+	val, ok = m.Lookup(Value_of[error](nil))
+	if !ok || val.Descr() != "1" {
+		t.Errorf("Expect 1 for the nil error key")
+	}
+	_, ok = m.Lookup(Value_of[error](io.EOF))
+	if ok {
+		t.Errorf("Expect no value for key EOF")
+	}
+}
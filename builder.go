@@ -0,0 +1,72 @@
+package shuntingyard
+
+/* common part of the builders, the expression being built */
+type builder struct {
+	e *Expr
+}
+
+// Return the expression being built, used to set its options before
+// Finalize.
+func (b *builder)Expr()(*Expr) {
+	return b.e
+}
+
+// Finalize the expression and return it
+func (b *builder)Finalize()(*Expr, error) {
+	var err error
+
+	err = b.e.Finalize()
+	if err != nil {
+		return nil, err
+	}
+	return b.e, nil
+}
+
+// Infix_builder builds expression in infix order using the shuntingyard
+// algorithm. It is Expr.Append without Push.
+type Infix_builder struct {
+	builder
+}
+
+func New_infix_builder(input_values [][]Type)(*Infix_builder) {
+	return &Infix_builder{
+		builder: builder{e: New(input_values)},
+	}
+}
+
+// Append element, like Expr.Append
+func (b *Infix_builder)Append(elt Elt)(error) {
+	return b.e.Append(elt)
+}
+
+// Rpn_builder builds expression in RPN order, the elements are pushed as
+// is. It is Expr.Push without Append.
+type Rpn_builder struct {
+	builder
+}
+
+func New_rpn_builder(input_values [][]Type)(*Rpn_builder) {
+	return &Rpn_builder{
+		builder: builder{e: New(input_values)},
+	}
+}
+
+// Push element, like Expr.Push
+func (b *Rpn_builder)Push(elt Elt)(error) {
+	return b.e.Push(elt)
+}
+
+// Return mark of the construction state, like Expr.Mark
+func (b *builder)Mark()(int) {
+	return b.e.Mark()
+}
+
+// Restore the construction state of mark, like Expr.Rewind
+func (b *builder)Rewind(mark int)(error) {
+	return b.e.Rewind(mark)
+}
+
+// Remove the last element, like Expr.Pop
+func (b *builder)Pop()(error) {
+	return b.e.Pop()
+}
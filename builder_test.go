@@ -0,0 +1,94 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+func Test_builder(t *testing.T) {
+	var add Elt
+	var mul Elt
+	var one Elt
+	var two Elt
+	var in *Infix_builder
+	var rpn *Rpn_builder
+	var e *Expr
+	var v []Value
+	var elt Elt
+	var err error
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	one = Constant("1", int64(1))
+	two = Constant("2", int64(2))
+
+	/* 1 + 2 * 2 */
+	in = New_infix_builder(nil)
+	for _, elt = range []Elt{one, add, two, mul, two} {
+		err = in.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	e, err = in.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "5" {
+		t.Errorf("Expect 5, got %v, %v", v, err)
+	}
+
+	/* 1 2 + 2 * */
+	rpn = New_rpn_builder(nil)
+	for _, elt = range []Elt{one, two, add, two, mul} {
+		err = rpn.Push(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	e, err = rpn.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "6" {
+		t.Errorf("Expect 6, got %v, %v", v, err)
+	}
+
+	/* the modes can't be mixed */
+	e = New(nil)
+	err = e.Append(one)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	err = e.Push(two)
+	if err == nil || err.Error() != `Expression built with Append, can't Push "2"` {
+		t.Errorf("Expect mixing error, got %v", err)
+	}
+	e = New(nil)
+	err = e.Push(one)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	err = e.Append(add)
+	if err == nil || err.Error() != `Expression built with Push, can't Append "+"` {
+		t.Errorf("Expect mixing error, got %v", err)
+	}
+
+	/* a rejected element doesn't set the mode */
+	e = New(nil)
+	err = e.Append(add)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Push(one)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	rpn = New_rpn_builder(nil)
+	rpn.Push(one)
+	err = rpn.Expr().Append(two)
+	if err == nil {
+		t.Errorf("Expect mixing error, got no error")
+	}
+}
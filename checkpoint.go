@@ -0,0 +1,66 @@
+package shuntingyard
+
+import "fmt"
+
+// Return mark of the current construction state, which is the number of
+// elements appended or pushed. Rewind restores the state of a mark.
+func (e *Expr)Mark()(int) {
+	return len(e.elements)
+}
+
+// Restore the construction state of the mark returned by Mark: the rpn,
+// the precedence stack and the name are rebuilt from the elements added
+// before the mark. The construction mode is kept, so an expression built
+// with Append can't be continued with Push after a rewind, and the
+// reverse. Not available after Finalize. If an element is rejected by
+// the replay, the state before the call is restored and the error is
+// returned.
+func (e *Expr)Rewind(mark int)(error) {
+	var elements []Elt
+	var elt Elt
+	var mode int
+	var saved Expr
+	var err error
+
+	if e.done {
+		return fmt.Errorf("Expression already finalized")
+	}
+	if mark < 0 || mark > len(e.elements) {
+		return fmt.Errorf("Invalid mark %d, the expression has %d elements", mark, len(e.elements))
+	}
+
+	elements = e.elements[:mark]
+	mode = e.mode
+	saved = *e
+	e.rpn = nil
+	e.precedence_stack = nil
+	e.name_elements = nil
+	e.elements = nil
+	e.operand = false
+	e.slots = nil
+
+	/* the elements were accepted in this order, but an element may
+	 * answer differently now. the replay builds new slices, so the
+	 * saved state is untouched.
+	 */
+	for _, elt = range elements {
+		if mode == mode_push {
+			err = e.Push(elt)
+		} else {
+			err = e.Append(elt)
+		}
+		if err != nil {
+			*e = saved
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove the last element appended or pushed
+func (e *Expr)Pop()(error) {
+	if len(e.elements) == 0 {
+		return fmt.Errorf("Expression error, no element to remove")
+	}
+	return e.Rewind(len(e.elements) - 1)
+}
@@ -0,0 +1,167 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+/* value element which becomes a group close when closed is set */
+type flip_elt struct {
+	closed bool
+}
+
+func (f *flip_elt)Precedence()(int) { return 0 }
+func (f *flip_elt)Associativity()(int) { return 0 }
+func (f *flip_elt)Kind()(int) {
+	if f.closed {
+		return Kind_group_close
+	}
+	return Kind_value
+}
+func (f *flip_elt)String()(string) { return "flip" }
+func (f *flip_elt)Input_types()([][]Type) { return nil }
+func (f *flip_elt)Output_types()([][]Type) { return [][]Type{[]Type{Type_of[int64]()}} }
+func (f *flip_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{Value_of(int64(3))}, nil
+}
+
+func Test_checkpoint(t *testing.T) {
+	var add Elt
+	var mul Elt
+	var one Elt
+	var two Elt
+	var e *Expr
+	var rpn *Rpn_builder
+	var v []Value
+	var mark int
+	var flip *flip_elt
+	var elt Elt
+	var err error
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	one = Constant("1", int64(1))
+	two = Constant("2", int64(2))
+
+	/* 1 + ( 2 * 2 ) rewound to 1 + then continued with 1 */
+	e = New(nil)
+	for _, elt = range []Elt{one, add} {
+		e.Append(elt)
+	}
+	mark = e.Mark()
+	for _, elt = range []Elt{op_open, two, mul, two, op_close} {
+		e.Append(elt)
+	}
+	err = e.Rewind(mark)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(e.rpn) != 1 || len(e.precedence_stack) != 1 || len(e.name_elements) != 2 {
+		t.Errorf("Expect the state of \"1 +\", got %d rpn, %d stacked, %d names",
+		         len(e.rpn), len(e.precedence_stack), len(e.name_elements))
+	}
+
+	/* the syntax state is restored, "1 + +" is rejected */
+	err = e.Append(add)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e.Append(two)
+	e.Append(mul)
+	e.Append(op_open)
+	err = e.Pop()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	e.Append(two)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if e.String() != "1 + 2 * 2" {
+		t.Errorf("Expect \"1 + 2 * 2\", got %q", e.String())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "5" {
+		t.Errorf("Expect 5, got %v, %v", v, err)
+	}
+	err = e.Pop()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* rpn builder */
+	rpn = New_rpn_builder(nil)
+	rpn.Push(one)
+	rpn.Push(two)
+	rpn.Push(mul)
+	err = rpn.Rewind(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	rpn.Push(add)
+	e, err = rpn.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "3" {
+		t.Errorf("Expect 3, got %v, %v", v, err)
+	}
+
+	/* invalid marks */
+	e = New(nil)
+	err = e.Pop()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e.Append(one)
+	err = e.Rewind(2)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Rewind(0)
+	if err != nil || e.Mark() != 0 {
+		t.Errorf("Expect empty expression, got %d elements, %v", e.Mark(), err)
+	}
+
+	/* the mode is kept by the rewind */
+	err = e.Push(one)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Finalize()
+	if err == nil || err.Error() != "Syntax error, missing operand" {
+		t.Errorf("Expect missing operand error, got %v", err)
+	}
+	rpn = New_rpn_builder(nil)
+	rpn.Push(one)
+	rpn.Rewind(0)
+	err = rpn.Expr().Append(one)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* a failed replay restores the state before the rewind */
+	flip = &flip_elt{}
+	e = New(nil)
+	for _, elt = range []Elt{one, add, flip, mul, two} {
+		e.Append(elt)
+	}
+	flip.closed = true
+	err = e.Rewind(4)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	if e.Mark() != 5 || len(e.rpn) != 3 || len(e.precedence_stack) != 2 {
+		t.Errorf("Expect the state of \"1 + flip * 2\", got %d elements, %d rpn, %d stacked",
+		         e.Mark(), len(e.rpn), len(e.precedence_stack))
+	}
+	flip.closed = false
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "7" {
+		t.Errorf("Expect 7, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,263 @@
+package shuntingyard
+
+import "fmt"
+import "sort"
+
+// Fit of the types of a suggested element, the best first
+const (
+	// the element provides or requires exactly the expected types
+	Fit_exact = iota
+	// the types are assignable to the expected types
+	Fit_assignable
+	// the types are converted to the expected types by the implicit conversions
+	Fit_convertible
+	// the types are generic or not known before Finalize
+	Fit_generic
+)
+
+// Element which can follow a partial expression
+type Suggestion struct {
+	Elt Elt
+	// use Fit_*
+	Fit int
+}
+
+// Elements which can follow a partial expression
+type Completion struct {
+	// kinds of the elements accepted next, use Kind_*
+	Kinds []int
+	// elements of the registry accepted next, ranked by type fit
+	Suggestions []Suggestion
+}
+
+/* return the fit of the provided types to the required types, -1 if
+ * they are not compatible. nil types are not known.
+ */
+func (e *Expr)type_fit(provide []Type, require []Type)(int) {
+	var t Type
+	var exact bool
+
+	if provide == nil || require == nil {
+		return Fit_generic
+	}
+	for _, t = range append(append([]Type(nil), provide...), require...) {
+		if type_has_var(t) {
+			return Fit_generic
+		}
+	}
+	exact = true
+	for _, t = range provide {
+		exact = exact && types_contain(require, t)
+	}
+	if exact {
+		return Fit_exact
+	}
+	if Has_compat(provide, require) {
+		return Fit_assignable
+	}
+	if e.conversions == nil || e.strict {
+		return -1
+	}
+	for _, t = range provide {
+		if !Has_compat([]Type{t}, require) && e.conversions.cheapest(t, require) == nil {
+			return -1
+		}
+	}
+	return Fit_convertible
+}
+
+/* return the types of the single value returned by the element, nil if
+ * they are known only by Finalize.
+ */
+func provided_types(ec *elt_cache)([]Type) {
+	var ok bool
+
+	if len(ec.output_types) != 1 {
+		return nil
+	}
+	_, ok = ec.elt.(Type_function)
+	if ok {
+		return nil
+	}
+	return ec.output_types[0]
+}
+
+/* return the types required for the next operand by the innermost
+ * pending operator, nil if they are not known.
+ */
+func (e *Expr)expected_types()([]Type) {
+	var shape *rpn_shape
+	var ec *elt_cache
+	var i int
+	var index int
+
+	for i = len(e.precedence_stack) - 1; i >= 0; i-- {
+		ec = e.precedence_stack[i]
+		if ec.kind != Kind_group_open {
+			break
+		}
+	}
+	if i < 0 {
+		return nil
+	}
+
+	/* the operands of the operator are pushed after its base */
+	shape = new_rpn_shape(e.rpn, len(e.input_types))
+	index = shape.depth[len(e.rpn)] - shape.depth[ec.base]
+	if ec.infix {
+		index++
+	}
+	if index < 0 || index >= len(ec.input_types) {
+		return nil
+	}
+	return ec.input_types[index]
+}
+
+/* return the number of pending operators popped by the operator ec */
+func (e *Expr)popped(ec *elt_cache)(int) {
+	var top *elt_cache
+	var n int
+
+	for n = 0; n < len(e.precedence_stack); n++ {
+		top = e.precedence_stack[len(e.precedence_stack) - 1 - n]
+		if top.kind == Kind_group_open {
+			break
+		}
+		if top.precedence > ec.precedence {
+			continue
+		}
+		if top.precedence == ec.precedence && top.associativity == Associativity_left {
+			continue
+		}
+		break
+	}
+	return n
+}
+
+/* return the types of the top of the stack once the n top pending
+ * operators are popped, nil if they are not known.
+ */
+func (e *Expr)top_types(n int)([]Type) {
+	var tmp *Expr
+	var c *type_checker
+	var ec *elt_cache
+	var cp *elt_cache
+	var i int
+	var err error
+
+	tmp = &Expr{
+		input_types: e.input_types,
+		conversions: e.conversions,
+		strict: e.strict,
+		macro: e.macro,
+	}
+	for _, ec = range e.rpn {
+		cp = &elt_cache{}
+		*cp = *ec
+		tmp.rpn = append(tmp.rpn, cp)
+	}
+	for i = 0; i < n; i++ {
+		cp = &elt_cache{}
+		*cp = *e.precedence_stack[len(e.precedence_stack) - 1 - i]
+		tmp.rpn = append(tmp.rpn, cp)
+	}
+	c, err = tmp.check_types()
+	if err != nil || len(c.types) == 0 {
+		return nil
+	}
+	return c.types[len(c.types) - 1]
+}
+
+/* return the kinds of the elements which can follow */
+func (e *Expr)next_kinds()([]int) {
+	var kinds []int
+	var slot syntax_slot
+
+	if !e.operand {
+		return []int{Kind_value, Kind_group_open, Kind_operator}
+	}
+	if len(e.slots) > 0 && e.slots[len(e.slots) - 1].open > 0 {
+		kinds = append(kinds, Kind_value, Kind_group_open)
+	}
+	for _, slot = range e.slots {
+		if slot.open == syntax_group {
+			kinds = append(kinds, Kind_group_close)
+			break
+		}
+	}
+	return append(kinds, Kind_operator)
+}
+
+/* return true if the element can follow, the state is not changed */
+func (e *Expr)accepts(ec *elt_cache)(bool) {
+	var operand bool
+	var slots []syntax_slot
+	var err error
+
+	operand = e.operand
+	slots = append([]syntax_slot(nil), e.slots...)
+	err = e.check_syntax(ec)
+	e.operand = operand
+	e.slots = slots
+	return err == nil
+}
+
+// Return the elements of the registry which can follow the partial
+// expression built with Append. The elements are checked against the
+// syntax, and against the types simulated on the partial expression:
+// the operands against the types required by the pending operator, the
+// operators against the type of their left operand. The incompatible
+// elements are removed, the others are sorted by Fit_*, the best first,
+// and in registration order.
+func (e *Expr)Complete(r *Registry)(*Completion, error) {
+	var out *Completion
+	var ec *elt_cache
+	var elt Elt
+	var expected []Type
+	var tops map[int][]Type
+	var n int
+	var ok bool
+	var fit int
+
+	if e.done {
+		return nil, fmt.Errorf("Expression already finalized")
+	}
+	if e.mode == mode_push {
+		return nil, fmt.Errorf("Completion needs expression built with Append")
+	}
+
+	out = &Completion{
+		Kinds: e.next_kinds(),
+	}
+
+	expected = e.expected_types()
+	tops = make(map[int][]Type)
+	for _, elt = range r.Elts() {
+		ec = new_elt_cache(elt)
+		if !e.accepts(ec) {
+			continue
+		}
+		switch {
+		case ec.kind == Kind_group_open || ec.kind == Kind_group_close:
+			fit = Fit_generic
+		case ec.kind == Kind_operator && e.operand && len(ec.input_types) > 0:
+			/* infix or postfix operator, its left operand is on the stack */
+			n = e.popped(ec)
+			_, ok = tops[n]
+			if !ok {
+				tops[n] = e.top_types(n)
+			}
+			fit = e.type_fit(tops[n], ec.input_types[0])
+		default:
+			fit = e.type_fit(provided_types(ec), expected)
+		}
+		if fit < 0 {
+			continue
+		}
+		out.Suggestions = append(out.Suggestions, Suggestion{Elt: elt, Fit: fit})
+	}
+	sort.SliceStable(out.Suggestions, func(i int, j int)(bool) {
+		return out.Suggestions[i].Fit < out.Suggestions[j].Fit
+	})
+	return out, nil
+}
@@ -0,0 +1,142 @@
+package shuntingyard
+
+import "strconv"
+import "strings"
+import "testing"
+
+/* type accepting the numbers */
+type number_type struct {}
+
+func (n *number_type)Name()(string) {
+	return "number"
+}
+func (n *number_type)Accepts(t Type)(bool) {
+	return t == Type_of[int64]() || t == Type_of[float64]()
+}
+
+/* return the suggestions as "symbol:fit" */
+func suggestions(c *Completion)(string) {
+	var out []string
+	var s Suggestion
+
+	for _, s = range c.Suggestions {
+		out = append(out, s.Elt.String() + ":" + strconv.Itoa(s.Fit))
+	}
+	return strings.Join(out, " ")
+}
+
+func Test_complete(t *testing.T) {
+	var r *Registry
+	var conv *Conversions
+	var scale Elt
+	var e *Expr
+	var c *Completion
+	var elt Elt
+	var err error
+	var k struct {
+		elts []Elt
+		conv bool
+		kinds []int
+		expect string
+	}
+
+	scale = &test{
+		precedence: 2,
+		associativity: Associativity_left,
+		kind: Kind_operator,
+		symbol: "scale",
+		input_types: [][]Type{[]Type{&number_type{}}, []Type{&number_type{}}},
+		output_types: [][]Type{[]Type{Type_of[float64]()}},
+	}
+	r = New_registry()
+	r.Add(
+		Constant("1", int64(1)),
+		Constant("0.5", float64(0.5)),
+		Constant("word", "word"),
+		Unary("inc", 3, Associativity_right, func(a int64)(int64, error) { return a + 1, nil }),
+		Binary("+.", 1, Associativity_left, func(a float64, b float64)(float64, error) { return a + b, nil }),
+		scale,
+		op_open,
+		op_close,
+	)
+	conv = New_conversions()
+	conv.Add(Unary("to_float", 0, Associativity_right, func(a int64)(float64, error) { return float64(a), nil }), 1)
+
+	for _, k = range []struct {
+		elts []Elt
+		conv bool
+		kinds []int
+		expect string
+	}{
+		/* nothing expected */
+		{nil, false, []int{Kind_value, Kind_group_open, Kind_operator},
+		 "1:3 0.5:3 word:3 inc:3 (:3"},
+		/* float64 expected */
+		{[]Elt{r.elts["0.5"], r.elts["+."]}, true, []int{Kind_value, Kind_group_open, Kind_operator},
+		 "0.5:0 1:2 inc:2 (:3"},
+		{[]Elt{r.elts["0.5"], r.elts["+."]}, false, []int{Kind_value, Kind_group_open, Kind_operator},
+		 "0.5:0 (:3"},
+		/* number expected */
+		{[]Elt{r.elts["1"], scale}, false, []int{Kind_value, Kind_group_open, Kind_operator},
+		 "1:1 0.5:1 inc:1 (:3"},
+		/* operators applied to int64 */
+		{[]Elt{r.elts["1"]}, true, []int{Kind_operator},
+		 "scale:1 +.:2"},
+		{[]Elt{op_open, r.elts["inc"], r.elts["1"]}, false, []int{Kind_group_close, Kind_operator},
+		 "scale:1 ):3"},
+		/* the left operand of +. is 0.5 scale 1 */
+		{[]Elt{r.elts["0.5"], scale, r.elts["1"]}, false, []int{Kind_operator},
+		 "+.:0 scale:1"},
+	} {
+		e = New(nil)
+		if k.conv {
+			e.Set_conversions(conv)
+		}
+		for _, elt = range k.elts {
+			err = e.Append(elt)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+		}
+		c, err = e.Complete(r)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+			continue
+		}
+		if !equal_ints(c.Kinds, k.kinds) {
+			t.Errorf("Expect kinds %v after %v, got %v", k.kinds, e.name_elements, c.Kinds)
+		}
+		if suggestions(c) != k.expect {
+			t.Errorf("Expect %q after %v, got %q", k.expect, e.name_elements, suggestions(c))
+		}
+	}
+
+	/* the completion needs an expression in construction built with Append */
+	e = New(nil)
+	e.Push(r.elts["1"])
+	_, err = e.Complete(r)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e = New(nil)
+	e.Append(r.elts["1"])
+	e.Finalize()
+	_, err = e.Complete(r)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+}
+
+func equal_ints(a []int, b []int)(bool) {
+	var i int
+
+	if len(a) != len(b) {
+		return false
+	}
+	for i = range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
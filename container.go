@@ -0,0 +1,112 @@
+package shuntingyard
+
+import "reflect"
+import "strings"
+
+// Value of type list<T>
+type List_value struct {
+	elem Type
+	Items []Value
+}
+
+func New_list_value(elem Type, items []Value)(*List_value) {
+	return &List_value{
+		elem: elem,
+		Items: items,
+	}
+}
+
+func (l *List_value)Descr()(string) {
+	var out []string
+	var v Value
+
+	for _, v = range l.Items {
+		out = append(out, v.Descr())
+	}
+	return "[" + strings.Join(out, ", ") + "]"
+}
+
+func (l *List_value)Type()(Type) {
+	return List_type(l.elem)
+}
+
+// Return the type of the items
+func (l *List_value)Elem()(Type) {
+	return l.elem
+}
+
+// Value of type map<K, V>. Keys and Values have the same length, the
+// value Values[i] is associated with the key Keys[i].
+type Map_value struct {
+	key Type
+	elem Type
+	Keys []Value
+	Values []Value
+}
+
+func New_map_value(key Type, elem Type)(*Map_value) {
+	return &Map_value{
+		key: key,
+		elem: elem,
+	}
+}
+
+func (m *Map_value)Descr()(string) {
+	var out []string
+	var i int
+
+	for i = range m.Keys {
+		out = append(out, m.Keys[i].Descr() + ": " + m.Values[i].Descr())
+	}
+	return "{" + strings.Join(out, ", ") + "}"
+}
+
+func (m *Map_value)Type()(Type) {
+	return Map_type(m.key, m.elem)
+}
+
+/* return true if the values a and b have the same type and the same
+ * value. The Go values are compared when they are available, else the
+ * descriptions are compared.
+ */
+func same_value(a Value, b Value)(bool) {
+	var ia interface{ Interface()(interface{}) }
+	var ib interface{ Interface()(interface{}) }
+	var va interface{}
+	var vb interface{}
+	var ok bool
+
+	if !Type_equal(a.Type(), b.Type()) {
+		return false
+	}
+	ia, ok = a.(interface{ Interface()(interface{}) })
+	if ok {
+		ib, ok = b.(interface{ Interface()(interface{}) })
+	}
+	if !ok {
+		return a.Descr() == b.Descr()
+	}
+	va = ia.Interface()
+	vb = ib.Interface()
+	/* nil interfaces, like a nil error, have no Go type */
+	if va == nil || vb == nil {
+		return va == nil && vb == nil
+	}
+	if reflect.TypeOf(va).Comparable() {
+		return va == vb
+	}
+	return a.Descr() == b.Descr()
+}
+
+// Return the value associated with key. The keys match if they have the
+// same type and the same value: the int64 1 doesn't match the string "1".
+func (m *Map_value)Lookup(key Value)(Value, bool) {
+	var i int
+
+	for i = range m.Keys {
+		if same_value(m.Keys[i], key) {
+			return m.Values[i], true
+		}
+	}
+	return nil, false
+}
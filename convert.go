@@ -0,0 +1,177 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strings"
+
+/* one registered conversion */
+type conversion struct {
+	elt Elt
+	from []Type
+	to []Type
+	cost int
+}
+
+// Registry of conversion elements. When Finalize finds an operand which
+// doesn't match the required type, it inserts the cheapest registered
+// conversion in the RPN.
+type Conversions struct {
+	list []*conversion
+}
+
+func New_conversions()(*Conversions) {
+	return &Conversions{}
+}
+
+// Register conversion element. The element must consume one value and
+// return one value, its Input_types is the converted type and its
+// Output_types is the produced type. The cost is used to choose between
+// many available conversions, the lower is preferred.
+func (c *Conversions)Add(elt Elt, cost int)(error) {
+	if len(elt.Input_types()) != 1 || len(elt.Output_types()) != 1 {
+		return fmt.Errorf("Conversion %q must have one input and one output, got %s",
+		                  elt.String(), Signature(elt))
+	}
+	c.list = append(c.list, &conversion{
+		elt: elt,
+		from: elt.Input_types()[0],
+		to: elt.Output_types()[0],
+		cost: cost,
+	})
+	return nil
+}
+
+// Use the registry c to insert implicit conversions during Finalize
+func (e *Expr)Set_conversions(c *Conversions) {
+	e.conversions = c
+}
+
+// Strict mode disable implicit conversions
+func (e *Expr)Set_strict(strict bool) {
+	e.strict = strict
+}
+
+/* return the cheapest conversion of type t to one of the required types */
+func (c *Conversions)cheapest(t Type, require []Type)(*conversion) {
+	var conv *conversion
+	var best *conversion
+
+	for _, conv = range c.list {
+		if !Has_compat([]Type{t}, conv.from) || !Has_compat(conv.to, require) {
+			continue
+		}
+		if best == nil || conv.cost < best.cost {
+			best = conv
+		}
+	}
+	return best
+}
+
+/* build conversion element for the operand index of the inputs,
+ * return nil if one alternative of the operand can't be converted.
+ */
+func (c *Conversions)plan(inputs [][]Type, index int, require []Type)(*conversion_elt) {
+	var ce *conversion_elt
+	var t Type
+	var conv *conversion
+	var types []Type
+
+	/* inputs is a part of the type stack, which is modified after */
+	ce = &conversion_elt{
+		index: index,
+		input_types: append([][]Type(nil), inputs...),
+	}
+	for _, t = range inputs[index] {
+		if Has_compat([]Type{t}, require) {
+			ce.from = append(ce.from, t)
+			ce.convs = append(ce.convs, nil)
+			if !Has_compat([]Type{t}, types) {
+				types = append(types, t)
+			}
+			continue
+		}
+		conv = c.cheapest(t, require)
+		if conv == nil {
+			return nil
+		}
+		ce.from = append(ce.from, t)
+		ce.convs = append(ce.convs, conv)
+		for _, t = range conv.to {
+			if !Has_compat([]Type{t}, types) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	ce.output_types = append([][]Type(nil), inputs...)
+	ce.output_types[index] = types
+	return ce
+}
+
+/* element inserted in the RPN which converts one operand. It consumes
+ * all the operands of the following element, and returns them with the
+ * operand index converted.
+ */
+type conversion_elt struct {
+	index int
+	input_types [][]Type
+	output_types [][]Type
+	// conversion to apply for each alternative type, nil if the type
+	// is kept as is
+	from []Type
+	convs []*conversion
+}
+
+func (c *conversion_elt)Precedence()(int) {
+	return 0
+}
+func (c *conversion_elt)Associativity()(int) {
+	return 0
+}
+func (c *conversion_elt)Kind()(int) {
+	return Kind_operator
+}
+func (c *conversion_elt)Input_types()([][]Type) {
+	return c.input_types
+}
+func (c *conversion_elt)Output_types()([][]Type) {
+	return c.output_types
+}
+func (c *conversion_elt)String()(string) {
+	var names []string
+	var conv *conversion
+
+	for _, conv = range c.convs {
+		if conv != nil {
+			names = append(names, conv.elt.String())
+		}
+	}
+	return strings.Join(names, "|")
+}
+func (c *conversion_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var out []Value
+	var val []Value
+	var i int
+	var err error
+
+	out = append([]Value(nil), in...)
+	for i = range c.from {
+		if !Is_assignable(in[c.index].Type(), c.from[i]) {
+			continue
+		}
+		if c.convs[i] == nil {
+			return out, nil
+		}
+		val, err = c.convs[i].elt.Execute(ctx, in[c.index:c.index + 1])
+		if err != nil {
+			return nil, err
+		}
+		if len(val) != 1 {
+			return nil, fmt.Errorf("Conversion %q returns %d values", c.convs[i].elt.String(), len(val))
+		}
+		out[c.index] = val[0]
+		return out, nil
+	}
+	return nil, fmt.Errorf("No conversion for operand #%d of type %s",
+	                       c.index, Type_string(in[c.index].Type()))
+}
@@ -0,0 +1,172 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+var op_int_3 *test_fn = &test_fn{
+	test: test{
+		kind: Kind_value,
+		symbol: "3",
+		output_types: [][]Type{[]Type{type_int64}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		return []Value{value_int64(3)}, nil
+	},
+}
+
+var op_int_or_nil *test_fn = &test_fn{
+	test: test{
+		kind: Kind_value,
+		symbol: "int_or_nil",
+		output_types: [][]Type{[]Type{type_int64,type_nil}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		return []Value{value_nil()}, nil
+	},
+}
+
+func conv_fn(symbol string, from type_t, to type_t, fn func(vs []Value)([]Value, error))(*test_fn) {
+	return &test_fn{
+		test: test{
+			kind: Kind_operator,
+			symbol: symbol,
+			input_types: [][]Type{[]Type{from}},
+			output_types: [][]Type{[]Type{to}},
+		},
+		fn: fn,
+	}
+}
+
+func Test_conversions(t *testing.T) {
+	var c *Conversions
+	var e *Expr
+	var err error
+	var v []Value
+	var ctx context.Context
+	var inputs [][]Type
+	var ce *conversion_elt
+
+	ctx = With_checked(context.Background())
+
+	c = New_conversions()
+	err = c.Add(op_add, 1)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	c.Add(conv_fn("int_to_string", type_int64, type_string, func(vs []Value)([]Value, error) {
+		return []Value{value_string("3")}, nil
+	}), 1)
+	c.Add(conv_fn("int_to_float_slow", type_int64, type_float64, func(vs []Value)([]Value, error) {
+		return nil, nil
+	}), 10)
+	c.Add(conv_fn("int_to_float", type_int64, type_float64, func(vs []Value)([]Value, error) {
+		return []Value{value_float64(float64(vs[0].(*value_t).value_int64))}, nil
+	}), 1)
+	c.Add(conv_fn("nil_to_float", type_nil, type_float64, func(vs []Value)([]Value, error) {
+		return []Value{value_float64(0)}, nil
+	}), 1)
+
+	/* without conversion */
+	e = New(nil)
+	e.Append(op_int_3)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* conversion of first operand */
+	e = New(nil)
+	e.Set_conversions(c)
+	e.Append(op_int_3)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	verif(t, e, "3|2.3|int_to_float|+|")
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "5.300000" {
+		t.Errorf("Expect 5.3, got %s", v[0].Descr())
+	}
+	if e.String() != "3 + 2.3" {
+		t.Errorf("Expect \"3 + 2.3\", got %q", e.String())
+	}
+
+	/* conversion of both operands */
+	e = New(nil)
+	e.Set_conversions(c)
+	e.Append(op_int_3)
+	e.Append(op_add)
+	e.Append(op_int_3)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	verif(t, e, "3|3|int_to_float|int_to_float|+|")
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "6.000000" {
+		t.Errorf("Expect 6, got %s", v[0].Descr())
+	}
+
+	/* conversion of alternatives */
+	e = New(nil)
+	e.Set_conversions(c)
+	e.Append(op_26_or_nil)
+	e.Append(op_add)
+	e.Append(op_int_or_nil)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	verif(t, e, "2.6_or_nil|int_or_nil|nil_to_float|int_to_float|nil_to_float|+|")
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "2.600000" {
+		t.Errorf("Expect 2.6, got %s", v[0].Descr())
+	}
+
+	/* strict mode */
+	e = New(nil)
+	e.Set_conversions(c)
+	e.Set_strict(true)
+	e.Append(op_int_3)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* no conversion available */
+	e = New(nil)
+	e.Set_conversions(c)
+	e.Append(op_true)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* the conversion keeps its own copy of the operand types, the type
+	 * stack it is planned from is modified after
+	 */
+	inputs = [][]Type{[]Type{type_int64}, []Type{type_float64}}
+	ce = c.plan(inputs, 0, []Type{type_float64})
+	if ce == nil {
+		t.Fatalf("Expect conversion of int64 to float64")
+	}
+	inputs[0] = []Type{type_string}
+	inputs[1] = []Type{type_bool}
+	if Type_list(ce.Input_types()) != "int64, float64" || Type_list(ce.Output_types()) != "float64, float64" {
+		t.Errorf("Expect (int64, float64) -> (float64, float64), got %s", Signature(ce))
+	}
+}
@@ -0,0 +1,262 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+
+/* execution frame of the debugger, one per entered expression */
+type debug_frame struct {
+	expr *Expr
+	pc int
+	stack []Value
+}
+
+// Stepping evaluator over the RPN of an expression. The debugger
+// executes one element per Step. Nested expressions are executed
+// as one element by Step, or entered using Step_into.
+type Debugger struct {
+	ctx context.Context
+	opts exec_opts
+	frames []*debug_frame
+	result []Value
+	err error
+	break_symbols map[string]bool
+	break_positions map[*Expr]map[int]bool
+}
+
+// Create a debugger which evaluate the finalized expression e
+// with inputs in.
+func New_debugger(ctx context.Context, e *Expr, in []Value)(*Debugger) {
+	var d *Debugger
+
+	d = &Debugger{
+		ctx: ctx,
+		opts: get_exec_opts(ctx),
+		break_symbols: make(map[string]bool),
+		break_positions: make(map[*Expr]map[int]bool),
+	}
+	d.frames = append(d.frames, &debug_frame{
+		expr: e,
+		stack: append([]Value(nil), in...),
+	})
+	d.settle()
+	return d
+}
+
+// Set breakpoint on each element displayed as symbol
+func (d *Debugger)Break_symbol(symbol string) {
+	d.break_symbols[symbol] = true
+}
+
+// Set breakpoint on the element at position pos of the expression e
+func (d *Debugger)Break_position(e *Expr, pos int) {
+	if d.break_positions[e] == nil {
+		d.break_positions[e] = make(map[int]bool)
+	}
+	d.break_positions[e][pos] = true
+}
+
+// Remove all breakpoints
+func (d *Debugger)Clear_breakpoints() {
+	d.break_symbols = make(map[string]bool)
+	d.break_positions = make(map[*Expr]map[int]bool)
+}
+
+// Return true if the evaluation is terminated, successfully or not
+func (d *Debugger)Done()(bool) {
+	return len(d.frames) == 0
+}
+
+// Return the result of the evaluation. It is available when Done
+// returns true.
+func (d *Debugger)Result()([]Value, error) {
+	return d.result, d.err
+}
+
+// Return the expression and the position of the next executed element.
+// The returned expression is nil if the evaluation is terminated.
+func (d *Debugger)Position()(*Expr, int) {
+	var f *debug_frame
+
+	if d.Done() {
+		return nil, 0
+	}
+	f = d.frames[len(d.frames) - 1]
+	return f.expr, f.pc
+}
+
+// Return the next executed element, or nil if the evaluation is terminated
+func (d *Debugger)Current()(Elt) {
+	var f *debug_frame
+
+	if d.Done() {
+		return nil
+	}
+	f = d.frames[len(d.frames) - 1]
+	return f.expr.rpn[f.pc].elt
+}
+
+// Return the stack of the current expression. The bottom of the
+// stack is the first entry. The returned slice must not be modified.
+func (d *Debugger)Stack()([]Value) {
+	if d.Done() {
+		return nil
+	}
+	return d.frames[len(d.frames) - 1].stack
+}
+
+// Describe the stack of the current expression, one entry per value
+// using Descr and the type name.
+func (d *Debugger)Stack_desc()([]string) {
+	var out []string
+	var v Value
+
+	for _, v = range d.Stack() {
+		out = append(out, value_desc(v))
+	}
+	return out
+}
+
+// Return the current nesting level, 0 for the debugged expression
+func (d *Debugger)Depth()(int) {
+	return len(d.frames) - 1
+}
+
+/* stop evaluation with error */
+func (d *Debugger)fail(err error) {
+	d.frames = nil
+	d.result = nil
+	d.err = err
+}
+
+/* leave all the terminated frames, and transmit outputs to the parent frame */
+func (d *Debugger)settle() {
+	var f *debug_frame
+	var p *debug_frame
+	var err error
+
+	for len(d.frames) > 0 {
+		f = d.frames[len(d.frames) - 1]
+		if f.pc < len(f.expr.rpn) {
+			return
+		}
+		d.frames = d.frames[:len(d.frames) - 1]
+		if len(d.frames) == 0 {
+			d.result = f.stack
+			return
+		}
+		p = d.frames[len(d.frames) - 1]
+		if d.opts.checked {
+			err = check_outputs(p.expr.rpn[p.pc], p.pc, f.stack)
+			if err != nil {
+				d.fail(err)
+				return
+			}
+		}
+		p.stack = p.stack[:len(p.stack) - len(p.expr.rpn[p.pc].input_types)]
+		p.stack = append(p.stack, f.stack...)
+		p.pc++
+	}
+}
+
+// Execute the next element. Nested expressions are executed in one step.
+// Returns false when the evaluation is terminated.
+func (d *Debugger)Step()(bool) {
+	var f *debug_frame
+	var next int
+	var skip bool
+	var err error
+
+	if d.Done() {
+		return false
+	}
+	f = d.frames[len(d.frames) - 1]
+	next, f.stack, skip = f.expr.skip_operand(&d.opts, f.pc, f.stack)
+	if skip {
+		f.pc = next
+		d.settle()
+		return !d.Done()
+	}
+	f.stack, err = f.expr.exec_elt(d.ctx, &d.opts, f.pc, f.stack)
+	if err != nil {
+		d.fail(err)
+		return false
+	}
+	f.pc++
+	d.settle()
+	return !d.Done()
+}
+
+// Like Step, but if the next element is a nested expression, enter it
+// and stop before its first element.
+func (d *Debugger)Step_into()(bool) {
+	var f *debug_frame
+	var ec *elt_cache
+	var ex *Expr
+	var l *lazy_operand
+	var ok bool
+
+	if d.Done() {
+		return false
+	}
+	f = d.frames[len(d.frames) - 1]
+	l, ok = f.expr.lazy[f.pc]
+	if ok && l.skips(f.stack) {
+		return d.Step()
+	}
+	ec = f.expr.rpn[f.pc]
+	ex, ok = ec.elt.(*Expr)
+	if !ok {
+		return d.Step()
+	}
+	if len(f.stack) < len(ec.input_types) {
+		d.fail(fmt.Errorf("%q needs %d elements, only %d available",
+		                  ec.elt.String(), len(ec.input_types), len(f.stack)))
+		return false
+	}
+	d.frames = append(d.frames, &debug_frame{
+		expr: ex,
+		stack: append([]Value(nil), f.stack[len(f.stack) - len(ec.input_types):]...),
+	})
+	d.settle()
+	return !d.Done()
+}
+
+// Leave the current nested expression and stop after it.
+// Returns false when the evaluation is terminated.
+func (d *Debugger)Step_out()(bool) {
+	var depth int
+
+	depth = len(d.frames)
+	for !d.Done() && len(d.frames) >= depth {
+		d.Step()
+	}
+	return !d.Done()
+}
+
+/* return true if a breakpoint is set on the next element */
+func (d *Debugger)on_breakpoint()(bool) {
+	var f *debug_frame
+
+	f = d.frames[len(d.frames) - 1]
+	if d.break_positions[f.expr][f.pc] {
+		return true
+	}
+	return d.break_symbols[f.expr.rpn[f.pc].elt.String()]
+}
+
+// Run the evaluation until the next breakpoint, entering nested
+// expressions to find breakpoints. Returns false when the evaluation
+// is terminated.
+func (d *Debugger)Continue()(bool) {
+	var first bool
+
+	first = true
+	for !d.Done() {
+		if !first && d.on_breakpoint() {
+			return true
+		}
+		first = false
+		d.Step_into()
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package shuntingyard
+
+import "context"
+import "reflect"
+import "testing"
+
+func Test_debugger(t *testing.T) {
+	var e *Expr
+	var se *Expr
+	var d *Debugger
+	var ex *Expr
+	var pos int
+	var v []Value
+	var err error
+
+	se = New(nil)
+	se.Append(op_23)
+	se.Append(op_add)
+	se.Append(op_24)
+	se.Finalize()
+
+	e = New(nil)
+	e.Append(se)
+	e.Append(op_mul)
+	e.Append(op_25)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	/* step over the nested expression */
+	d = New_debugger(context.Background(), e, nil)
+	if d.Current() != se {
+		t.Errorf("Expect nested expression as current element, got %q", d.Current().String())
+	}
+	d.Step()
+	if len(d.Stack()) != 1 || d.Stack()[0].Descr() != "4.700000" {
+		t.Errorf("Expect stack (4.7), got %s", values_desc(d.Stack()))
+	}
+	if d.Current() != op_25 {
+		t.Errorf("Expect 2.5 as current element, got %q", d.Current().String())
+	}
+	for d.Step() {}
+	if !d.Done() {
+		t.Errorf("Expect terminated evaluation")
+	}
+	v, err = d.Result()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if len(v) != 1 || v[0].Descr() != "11.750000" {
+		t.Errorf("Expect result 11.75, got %s", values_desc(v))
+	}
+
+	/* step into the nested expression */
+	d = New_debugger(context.Background(), e, nil)
+	d.Step_into()
+	ex, pos = d.Position()
+	if ex != se || pos != 0 || d.Depth() != 1 {
+		t.Errorf("Expect position 0 in nested expression")
+	}
+	d.Step()
+	d.Step()
+	if !reflect.DeepEqual(d.Stack_desc(), []string{"2.300000:float64", "2.400000:float64"}) {
+		t.Errorf("Expect two values in the stack, got %q", d.Stack_desc())
+	}
+	d.Step_out()
+	ex, pos = d.Position()
+	if ex != e || pos != 1 || d.Depth() != 0 {
+		t.Errorf("Expect position 1 in main expression")
+	}
+
+	/* breakpoints */
+	d = New_debugger(context.Background(), e, nil)
+	d.Break_symbol("+")
+	d.Break_position(e, 2)
+	if !d.Continue() || d.Current() != op_add || d.Depth() != 1 {
+		t.Errorf("Expect stop on \"+\" in nested expression")
+	}
+	if !d.Continue() || d.Current() != op_mul {
+		t.Errorf("Expect stop on \"*\"")
+	}
+	if d.Continue() {
+		t.Errorf("Expect terminated evaluation")
+	}
+	v, err = d.Result()
+	if err != nil || len(v) != 1 {
+		t.Errorf("Expect one result")
+	}
+
+	/* errors stop the evaluation */
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(op_add_error)
+	e.Append(op_24)
+	e.Finalize()
+	d = New_debugger(context.Background(), e, nil)
+	d.Continue()
+	_, err = d.Result()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+}
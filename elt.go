@@ -41,3 +41,64 @@ type Elt interface {
 	// display element
 	String()(string)
 }
+
+// Optional interface implemented by the elements which compute their
+// output types from the input types found during Finalize. The input
+// types are the alternatives present in the simulated type stack, the
+// returned output types must be compatible with Output_types.
+type Type_function interface {
+	Infer_output_types(in [][]Type)([][]Type, error)
+}
+
+// Optional interface implemented by the elements which accept null types
+// in their Input_types but fail when they receive a null value. Finalize
+// records a warning when a nullable operand reaches such element.
+type Null_rejecter interface {
+	Rejects_null()(bool)
+}
+
+// Optional interface implemented by the guard operators, like "x != nil".
+// Narrow returns, for each operand, the types the operand has when the
+// guard returns result, or nil if the guard doesn't narrow the operand.
+// Finalize applies the narrowing to the variables checked by the guard
+// in the operands of the connectives which depend on the guard.
+type Guard interface {
+	Narrow(in [][]Type, result bool)([][]Type)
+}
+
+// Connectives, returned by the interface Connective
+const (
+	// operands (a, b), b is evaluated when a is true
+	Connective_and = iota
+	// operands (a, b), b is evaluated when a is false
+	Connective_or
+	// operand (a), the result is true when a is false
+	Connective_not
+	// operands (cond, then, else), then is evaluated when cond is true,
+	// else when cond is false
+	Connective_if
+)
+
+// Optional interface implemented by the elements which read a variable.
+// Variable returns a comparable key identifying the variable: the elements
+// returning equal keys read the same value, so the narrowing found by a
+// guard on one applies to the others. The elements which don't implement
+// it, like the literals, are never narrowed.
+type Variable interface {
+	Variable()(interface{})
+}
+
+// Optional interface implemented by the logical operators and the
+// conditionals. Finalize checks their operands with the narrowing of the
+// guards found in the condition, and Execute evaluates the operand index
+// (index > 0) only if Skip returns false. previous are the values of the
+// operands preceding index.
+//
+// The skipped operands are not computed: Execute receives nil at their
+// place in its inputs, so it must not read them. Typically "and" reads its
+// second operand only if the first one is true. The elements which don't
+// implement Connective always receive all their operands.
+type Connective interface {
+	Connective()(int)
+	Skip(index int, previous []Value)(bool)
+}
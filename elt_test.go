@@ -3,6 +3,7 @@ package shuntingyard
 import "context"
 import "fmt"
 import "reflect"
+import "strings"
 import "testing"
 
 type test struct {
@@ -21,6 +22,8 @@ func (t type_t) Name()(string) {
 	case type_nil: return "nil"
 	case type_bool: return "bool"
 	case type_float64: return "float64"
+	case type_string: return "string"
+	case type_int64: return "int64"
 	}
 	panic(fmt.Errorf("Unhandled type %#v", t))
 }
@@ -29,6 +32,8 @@ var type_float64 type_t = 0
 var type_bool type_t = 1
 var type_nil type_t = 2
 var type_other type_t = 3
+var type_string type_t = 4
+var type_int64 type_t = 5
 
 type value_t struct {
 	// type of value. use Type_*
@@ -37,6 +42,10 @@ type value_t struct {
 	value_float64 float64
 	// Bool
 	value_bool bool
+	// String
+	value_string string
+	// Int64
+	value_int64 int64
 }
 
 func (v *value_t)Descr()(string) {
@@ -44,6 +53,8 @@ func (v *value_t)Descr()(string) {
 	case type_nil:     return "nil"
 	case type_bool:    return fmt.Sprintf("%t", v.value_bool)
 	case type_float64: return fmt.Sprintf("%f", v.value_float64)
+	case type_string:  return fmt.Sprintf("%q", v.value_string)
+	case type_int64:   return fmt.Sprintf("%d", v.value_int64)
 	}
 	panic(fmt.Errorf("Unhandled type %#v", v.kind))
 }
@@ -72,6 +83,30 @@ func value_float64(v float64)(Value) {
 	}
 }
 
+func value_string(v string)(Value) {
+	return &value_t{
+		kind: type_t(type_string),
+		value_string: v,
+	}
+}
+
+func value_int64(v int64)(Value) {
+	return &value_t{
+		kind: type_t(type_int64),
+		value_int64: v,
+	}
+}
+
+/* test element executing a function */
+type test_fn struct {
+	test
+	fn func(vs []Value)([]Value, error)
+}
+
+func (t *test_fn)Execute(ctx context.Context, vs []Value)([]Value, error) {
+	return t.fn(vs)
+}
+
 func (t *test)Precedence()(int) { return t.precedence }
 func (t *test)Associativity()(int) { return t.associativity }
 func (t *test)Kind()(int) { return t.kind }
@@ -96,6 +131,9 @@ func (t *test)Execute(ctx context.Context, vs []Value)([]Value, error) {
 	case "2.5":  return []Value{value_float64(2.5)}, nil
 	case "2.6":  return []Value{value_float64(2.6)}, nil
 	case "2.6_or_nil":  return []Value{value_float64(2.6)}, nil
+	case "bad_count":  return []Value{value_float64(2.6), value_float64(2.6)}, nil
+	case "bad_type":  return []Value{value_bool(true)}, nil
+	case "bad_nil":  return []Value{nil}, nil
 	case "coalesce_float": return func(in []Value)([]Value, error) {
 		if in[0].(*value_t).kind == type_float64 {
 			return []Value{value_float64(in[0].(*value_t).value_float64)}, nil
@@ -107,6 +145,22 @@ func (t *test)Execute(ctx context.Context, vs []Value)([]Value, error) {
 	}
 }
 
+func (t *test)Decisive(in []Value, out []Value)([]int) {
+	var i int
+	var all []int
+
+	for i = range in {
+		switch {
+		case t.symbol == "and" && !in[i].(*value_t).value_bool:
+			return []int{i}
+		case t.symbol == "or" && in[i].(*value_t).value_bool:
+			return []int{i}
+		}
+		all = append(all, i)
+	}
+	return all
+}
+
 var op_open *test = &test{
 	kind: Kind_group_open,
 	symbol: "(",
@@ -229,6 +283,27 @@ var op_26_or_nil *test = &test{
 	output_types: [][]Type{[]Type{type_float64,type_nil}},
 }
 
+var op_bad_count *test = &test{
+	kind: Kind_value,
+	symbol: "bad_count",
+	input_types: [][]Type{},
+	output_types: [][]Type{[]Type{type_float64}},
+}
+
+var op_bad_type *test = &test{
+	kind: Kind_value,
+	symbol: "bad_type",
+	input_types: [][]Type{},
+	output_types: [][]Type{[]Type{type_float64}},
+}
+
+var op_bad_nil *test = &test{
+	kind: Kind_value,
+	symbol: "bad_nil",
+	input_types: [][]Type{},
+	output_types: [][]Type{[]Type{type_float64}},
+}
+
 func verif(t *testing.T, e *Expr, expect string) {
 	var sol string
 	var ec *elt_cache
@@ -350,15 +425,19 @@ func Test_expr(t *testing.T) {
 
 	e = New(nil)
 	e.Append(op_23)
-	e.Append(op_open)
+	err = e.Append(op_open)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
 	e.Append(op_add)
 	e.Append(op_24)
 	e.Append(op_mul)
 	e.Append(op_25)
 	err = e.Finalize()
-	if err == nil {
-		t.Errorf("Expect error, got no error")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
 	}
+	verif(t, e, "2.3|2.4|2.5|*|+|")
 
 	e = New(nil)
 	e.Append(op_open)
@@ -429,18 +508,32 @@ func Test_expr(t *testing.T) {
 
 	e = New(nil)
 	e.Append(op_23)
-	e.Append(op_23)
+	err = e.Append(op_23)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Finalize()
+	if !reflect.DeepEqual(e.output_types, [][]Type{[]Type{type_float64}}) {
+		t.Errorf("Expect \"float64\" output types, got %q", Type_list(e.output_types))
+	}
+
+	e = New(nil)
+	e.Push(op_23)
+	e.Push(op_23)
 	err = e.Finalize()
 	if !reflect.DeepEqual(e.output_types, [][]Type{[]Type{type_float64}, []Type{type_float64}}) {
 		t.Errorf("Expect \"float64, float64\" output types, got %q", Type_list(e.output_types))
 	}
 
 	e = New(nil)
-	e.Append(op_add)
-	err = e.Finalize()
+	err = e.Append(op_add)
 	if err == nil {
 		t.Errorf("Expect error, got no error")
 	}
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
 
 	e = New(nil)
 	e.Append(op_23)
@@ -505,8 +598,18 @@ func Test_exec(t *testing.T) {
 	}
 
 	e = New(nil)
-	e.Append(op_add)
-	e.Append(op_add)
+	err = e.Append(op_add)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Append(op_add)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	e = New(nil)
+	e.Push(op_add)
+	e.Push(op_add)
 	e.Finalize() // error intentionnaly not check
 	e.done = true // force done
 	_, err = e.Execute(ctx, nil)
@@ -719,3 +822,172 @@ func Test_sub_expression(t *testing.T) {
 		}
 	}
 }
+
+func Test_checked(t *testing.T) {
+	var e *Expr
+	var se *Expr
+	var err error
+	var v []Value
+	var ctx context.Context
+	var bad *test
+
+	ctx = With_checked(context.Background())
+
+	e = New(nil)
+	e.Append(op_26_or_nil)
+	e.Append(op_coalesce_float)
+	e.Append(op_26)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if len(v) != 1 {
+		t.Errorf("Expect only one value as return, got %d", len(v))
+	}
+
+	for _, bad = range []*test{op_bad_count, op_bad_type, op_bad_nil} {
+		e = New(nil)
+		e.Append(op_26)
+		e.Append(op_add)
+		e.Append(bad)
+		err = e.Finalize()
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+		}
+
+		/* unchecked mode trust the element */
+		if bad != op_bad_nil {
+			_, err = e.Execute(context.Background(), nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err.Error())
+			}
+		}
+
+		_, err = e.Execute(ctx, nil)
+		if err == nil {
+			t.Errorf("Expect error for %q, got no error", bad.symbol)
+		} else if !strings.Contains(err.Error(), fmt.Sprintf("%q at position 1", bad.symbol)) {
+			t.Errorf("Expect error reporting %q at position 1, got %q", bad.symbol, err.Error())
+		}
+	}
+
+	/* the checked mode is applied on nested expressions */
+	se = New(nil)
+	se.Push(op_bad_type)
+	se.Finalize()
+	e = New(nil)
+	e.Append(op_26)
+	e.Append(op_add)
+	e.Append(se)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	_, err = e.Execute(ctx, nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if !strings.Contains(err.Error(), "\"bad_type\" at position 0") {
+		t.Errorf("Expect error reporting nested element, got %q", err.Error())
+	}
+}
+
+/* test element with output types computed from input types */
+type test_type_fn struct {
+	test_fn
+	infer func(in [][]Type)([][]Type, error)
+}
+
+func (t *test_type_fn)Infer_output_types(in [][]Type)([][]Type, error) {
+	return t.infer(in)
+}
+
+var op_coalesce *test_type_fn = &test_type_fn{
+	test_fn: test_fn{
+		test: test{
+			precedence: 2,
+			associativity: Associativity_left,
+			kind: Kind_operator,
+			symbol: "coalesce",
+			input_types: [][]Type{[]Type{type_float64,type_string,type_nil},[]Type{type_float64,type_string}},
+			output_types: [][]Type{[]Type{type_float64,type_string}},
+		},
+		fn: func(vs []Value)([]Value, error) {
+			if vs[0].Type() == type_nil {
+				return []Value{vs[1]}, nil
+			}
+			return []Value{vs[0]}, nil
+		},
+	},
+	infer: func(in [][]Type)([][]Type, error) {
+		var t Type
+		var out []Type
+
+		for _, t = range in[0] {
+			if t != type_nil && !Has_compat([]Type{t}, out) {
+				out = append(out, t)
+			}
+		}
+		for _, t = range in[1] {
+			if !Has_compat([]Type{t}, out) {
+				out = append(out, t)
+			}
+		}
+		if len(out) > 1 {
+			return nil, fmt.Errorf("operands must have the same type, got %s", Type_desc(out))
+		}
+		return [][]Type{out}, nil
+	},
+}
+
+func Test_type_function(t *testing.T) {
+	var e *Expr
+	var err error
+	var v []Value
+
+	/* without inference, the union output is rejected by "+" */
+	e = New(nil)
+	e.Append(op_26_or_nil)
+	e.Append(&op_coalesce.test_fn)
+	e.Append(op_26)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	e = New(nil)
+	e.Append(op_26_or_nil)
+	e.Append(op_coalesce)
+	e.Append(op_26)
+	e.Append(op_add)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if Type_list(e.Output_types()) != "float64" {
+		t.Errorf("Expect \"float64\", got %q", Type_list(e.Output_types()))
+	}
+	v, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "4.900000" {
+		t.Errorf("Expect 4.9, got %s", v[0].Descr())
+	}
+
+	/* type function error */
+	e = New(nil)
+	e.Append(op_26)
+	e.Append(op_coalesce)
+	e.Append(op_str_a)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if !strings.Contains(err.Error(), "same type") {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
@@ -0,0 +1,239 @@
+package shuntingyard
+
+import "context"
+import "encoding/json"
+import "fmt"
+import "strings"
+import "time"
+
+// Optional interface implemented by the elements which are able to
+// tell which inputs determined their result. Typically "and" returning
+// false is determined by its first false input. Decisive returns the
+// index of the inputs which determined the outputs. Elements which do
+// not implement this interface depends on all their inputs.
+type Decider interface {
+	Decisive(in []Value, out []Value)([]int)
+}
+
+// One node of the evaluated tree. A node is an element execution
+// or an input of the executed expression.
+type Explain_node struct {
+	// element symbol or input designation
+	Symbol string `json:"symbol"`
+	// description of the produced values
+	Values []string `json:"values"`
+	// true if the node contributes to the final result
+	Decisive bool `json:"decisive"`
+	// nodes which produce the inputs of the element
+	Children []*Explain_node `json:"children,omitempty"`
+	// for the nested expressions, the nodes which produce the outputs
+	// of the nested expression
+	Body []*Explain_node `json:"body,omitempty"`
+	elt Elt
+	in []Value
+	out []Value
+}
+
+// Result of Explain. Root contains one node per returned value.
+type Explanation struct {
+	Result []Value `json:"-"`
+	Root []*Explain_node `json:"root"`
+}
+
+/* execution frame of the explain tracer */
+type explain_frame struct {
+	slots []*Explain_node
+}
+
+/* tracer which build the evaluated tree */
+type explain_tracer struct {
+	frames []*explain_frame
+	// inputs of the next entered expression
+	pending_in []*Explain_node
+	// outputs of the last leaved expression
+	pending_body []*Explain_node
+	// tracer found in the context, it receive the events too
+	next Tracer
+}
+
+func (x *explain_tracer)Enter(e *Expr, in []Value) {
+	var f *explain_frame
+	var i int
+
+	if x.next != nil {
+		x.next.Enter(e, in)
+	}
+	f = &explain_frame{}
+	if len(x.frames) == 0 {
+		for i = range in {
+			f.slots = append(f.slots, &Explain_node{
+				Symbol: fmt.Sprintf("input #%d", i),
+				Values: []string{value_desc(in[i])},
+			})
+		}
+	} else {
+		f.slots = append(f.slots, x.pending_in...)
+	}
+	x.pending_in = nil
+	x.frames = append(x.frames, f)
+}
+
+func (x *explain_tracer)Leave(e *Expr, out []Value, err error, d time.Duration) {
+	var f *explain_frame
+
+	if x.next != nil {
+		x.next.Leave(e, out, err, d)
+	}
+	f = x.frames[len(x.frames) - 1]
+	x.frames = x.frames[:len(x.frames) - 1]
+	x.pending_body = f.slots
+}
+
+func (x *explain_tracer)Before(e *Expr, pos int, elt Elt, in []Value) {
+	var f *explain_frame
+
+	if x.next != nil {
+		x.next.Before(e, pos, elt, in)
+	}
+	f = x.frames[len(x.frames) - 1]
+	x.pending_in = f.slots[len(f.slots) - len(in):]
+	x.pending_body = nil
+}
+
+func (x *explain_tracer)After(e *Expr, pos int, elt Elt, in []Value, out []Value, err error, d time.Duration) {
+	var f *explain_frame
+	var n *Explain_node
+	var v Value
+	var i int
+	var ok bool
+
+	if x.next != nil {
+		x.next.After(e, pos, elt, in, out, err, d)
+	}
+	if err != nil {
+		return
+	}
+	f = x.frames[len(x.frames) - 1]
+	n = &Explain_node{
+		Symbol: elt.String(),
+		Children: append([]*Explain_node(nil), f.slots[len(f.slots) - len(in):]...),
+		elt: elt,
+		in: copy_values(in),
+		out: copy_values(out),
+	}
+	_, ok = elt.(*Expr)
+	if ok {
+		n.Body = x.pending_body
+	}
+	x.pending_body = nil
+	for _, v = range out {
+		n.Values = append(n.Values, value_desc(v))
+	}
+	f.slots = f.slots[:len(f.slots) - len(in)]
+	for i = 0; i < len(out); i++ {
+		f.slots = append(f.slots, n)
+	}
+}
+
+/* mark node and nodes which determine it as decisive */
+func (n *Explain_node)mark() {
+	var c *Explain_node
+	var d Decider
+	var ok bool
+	var i int
+
+	if n.Decisive {
+		return
+	}
+	n.Decisive = true
+
+	/* nested expression, the result is determined by its body */
+	if _, ok = n.elt.(*Expr); ok {
+		for _, c = range n.Body {
+			c.mark()
+		}
+		return
+	}
+
+	d, ok = n.elt.(Decider)
+	if !ok {
+		for _, c = range n.Children {
+			c.mark()
+		}
+		return
+	}
+	for _, i = range d.Decisive(n.in, n.out) {
+		if i >= 0 && i < len(n.Children) {
+			n.Children[i].mark()
+		}
+	}
+}
+
+// Execute the expression and return the evaluated tree annotated with
+// each sub-result. The nodes which determine the final result are marked
+// as decisive. A tracer already set in the context with With_tracer
+// still receive the execution events.
+func (e *Expr)Explain(ctx context.Context, in []Value)(*Explanation, error) {
+	var x *explain_tracer
+	var out []Value
+	var err error
+	var xp *Explanation
+	var n *Explain_node
+
+	x = &explain_tracer{
+		next: get_exec_opts(ctx).tracer,
+	}
+	out, err = e.Execute(With_tracer(ctx, x), in)
+	if err != nil {
+		return nil, err
+	}
+
+	xp = &Explanation{
+		Result: out,
+		Root: x.pending_body,
+	}
+	for _, n = range xp.Root {
+		n.mark()
+	}
+	return xp, nil
+}
+
+func (n *Explain_node)render(b *strings.Builder, level int) {
+	var c *Explain_node
+	var children []*Explain_node
+	var ok bool
+
+	if n.Decisive {
+		b.WriteString("* ")
+	} else {
+		b.WriteString("  ")
+	}
+	b.WriteString(strings.Repeat("    ", level))
+	_, ok = n.elt.(*Expr)
+	if ok {
+		fmt.Fprintf(b, "[%s] -> %s\n", n.Symbol, strings.Join(n.Values, ", "))
+		children = n.Body
+	} else {
+		fmt.Fprintf(b, "%s -> %s\n", n.Symbol, strings.Join(n.Values, ", "))
+		children = n.Children
+	}
+	for _, c = range children {
+		c.render(b, level + 1)
+	}
+}
+
+// Render the evaluated tree as text. Decisive nodes are prefixed by "*"
+func (xp *Explanation)String()(string) {
+	var b strings.Builder
+	var n *Explain_node
+
+	for _, n = range xp.Root {
+		n.render(&b, 0)
+	}
+	return b.String()
+}
+
+// Render the evaluated tree as JSON
+func (xp *Explanation)JSON()([]byte, error) {
+	return json.Marshal(xp)
+}
@@ -0,0 +1,88 @@
+package shuntingyard
+
+import "context"
+import "encoding/json"
+import "testing"
+
+func Test_explain(t *testing.T) {
+	var e *Expr
+	var se *Expr
+	var xp *Explanation
+	var err error
+	var expect string
+	var data []byte
+	var decoded map[string]interface{}
+	var r *Recorder
+
+	se = New(nil)
+	se.Append(op_false)
+	se.Append(op_or)
+	se.Append(op_true)
+	se.Finalize()
+
+	e = New(nil)
+	e.Append(op_false)
+	e.Append(op_and)
+	e.Append(op_true)
+	e.Append(op_or)
+	e.Append(se)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	xp, err = e.Explain(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	expect = "* or -> true:bool\n" +
+	         "      and -> false:bool\n" +
+	         "          false -> false:bool\n" +
+	         "          true -> true:bool\n" +
+	         "*     [false or true] -> true:bool\n" +
+	         "*         or -> true:bool\n" +
+	         "              false -> false:bool\n" +
+	         "*             true -> true:bool\n"
+	if xp.String() != expect {
+		t.Errorf("Expect explanation:\n%s\ngot:\n%s", expect, xp.String())
+	}
+
+	data, err = xp.JSON()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if len(decoded["root"].([]interface{})) != 1 {
+		t.Errorf("Expect one root node, got %s", string(data))
+	}
+
+	/* without decider, all the inputs are decisive */
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(op_add)
+	e.Append(op_24)
+	e.Finalize()
+	xp, err = e.Explain(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	expect = "* + -> 4.700000:float64\n" +
+	         "*     2.3 -> 2.300000:float64\n" +
+	         "*     2.4 -> 2.400000:float64\n"
+	if xp.String() != expect {
+		t.Errorf("Expect explanation:\n%s\ngot:\n%s", expect, xp.String())
+	}
+
+	/* the tracer of the context receives the events too */
+	r = New_recorder()
+	_, err = e.Explain(With_tracer(context.Background(), r), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(r.Steps) != 5 || r.Steps[0].Event != Trace_enter || r.Steps[4].Event != Trace_leave {
+		t.Errorf("Expect 5 steps from enter to leave, got %d", len(r.Steps))
+	}
+}
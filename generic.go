@@ -0,0 +1,198 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "reflect"
+import "sync"
+
+// Type of the Go values used by the generic API. There is one instance
+// per Go type, so the types can be compared. The name is the Go type name.
+type Go_type struct {
+	rt reflect.Type
+}
+
+/* cache of Go_type indexed by reflect.Type */
+var go_types sync.Map
+
+// Return the Type associated with the Go type rt
+func Go_type_of(rt reflect.Type)(*Go_type) {
+	var t interface{}
+
+	t, _ = go_types.LoadOrStore(rt, &Go_type{rt: rt})
+	return t.(*Go_type)
+}
+
+// Return the Type associated with the Go type T
+func Type_of[T any]()(*Go_type) {
+	return Go_type_of(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func (t *Go_type)Name()(string) {
+	return t.rt.String()
+}
+
+// Return the Go type
+func (t *Go_type)Reflect()(reflect.Type) {
+	return t.rt
+}
+
+// Value wrapping a Go value of type T
+type Typed[T any] struct {
+	V T
+}
+
+// Wrap the Go value v
+func Value_of[T any](v T)(*Typed[T]) {
+	return &Typed[T]{V: v}
+}
+
+func (v *Typed[T])Descr()(string) {
+	return fmt.Sprint(v.V)
+}
+
+func (v *Typed[T])Type()(Type) {
+	return Type_of[T]()
+}
+
+// Return the wrapped Go value
+func (v *Typed[T])Interface()(interface{}) {
+	return v.V
+}
+
+// Return the Go value of type T stored in v. The value must be built
+// by Value_of, or it must provide the Go value with an Interface method.
+func Get[T any](v Value)(T, error) {
+	var tv *Typed[T]
+	var iv interface{ Interface()(interface{}) }
+	var out T
+	var ok bool
+
+	if v == nil {
+		return out, fmt.Errorf("Expect %s, got nil value", Type_of[T]().Name())
+	}
+	tv, ok = v.(*Typed[T])
+	if ok {
+		return tv.V, nil
+	}
+	iv, ok = v.(interface{ Interface()(interface{}) })
+	if ok {
+		out, ok = iv.Interface().(T)
+		if ok {
+			return out, nil
+		}
+	}
+	return out, fmt.Errorf("Expect %s, got %s", Type_of[T]().Name(), Type_string(v.Type()))
+}
+
+/* element built by the generic constructors */
+type go_elt struct {
+	symbol string
+	precedence int
+	associativity int
+	kind int
+	input_types [][]Type
+	output_types [][]Type
+	exec func(in []Value)([]Value, error)
+}
+
+func (g *go_elt)Precedence()(int) {
+	return g.precedence
+}
+func (g *go_elt)Associativity()(int) {
+	return g.associativity
+}
+func (g *go_elt)Kind()(int) {
+	return g.kind
+}
+func (g *go_elt)String()(string) {
+	return g.symbol
+}
+func (g *go_elt)Input_types()([][]Type) {
+	return g.input_types
+}
+func (g *go_elt)Output_types()([][]Type) {
+	return g.output_types
+}
+func (g *go_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	if len(in) != len(g.input_types) {
+		return nil, fmt.Errorf("%q needs %d values, got %d", g.symbol, len(g.input_types), len(in))
+	}
+	return g.exec(in)
+}
+
+// Create value element returning the constant v
+func Constant[R any](symbol string, v R)(Elt) {
+	var val Value
+
+	val = Value_of(v)
+	return &go_elt{
+		symbol: symbol,
+		kind: Kind_value,
+		input_types: [][]Type{},
+		output_types: [][]Type{[]Type{Type_of[R]()}},
+		exec: func(in []Value)([]Value, error) {
+			return []Value{val}, nil
+		},
+	}
+}
+
+// Create unary operator calling fn. The input and output types are
+// derived from the Go types A and R.
+func Unary[A any, R any](symbol string, precedence int, associativity int, fn func(A)(R, error))(Elt) {
+	return &go_elt{
+		symbol: symbol,
+		precedence: precedence,
+		associativity: associativity,
+		kind: Kind_operator,
+		input_types: [][]Type{[]Type{Type_of[A]()}},
+		output_types: [][]Type{[]Type{Type_of[R]()}},
+		exec: func(in []Value)([]Value, error) {
+			var a A
+			var r R
+			var err error
+
+			a, err = Get[A](in[0])
+			if err != nil {
+				return nil, err
+			}
+			r, err = fn(a)
+			if err != nil {
+				return nil, err
+			}
+			return []Value{Value_of(r)}, nil
+		},
+	}
+}
+
+// Create binary operator calling fn. The input and output types are
+// derived from the Go types A, B and R.
+func Binary[A any, B any, R any](symbol string, precedence int, associativity int, fn func(A, B)(R, error))(Elt) {
+	return &go_elt{
+		symbol: symbol,
+		precedence: precedence,
+		associativity: associativity,
+		kind: Kind_operator,
+		input_types: [][]Type{[]Type{Type_of[A]()}, []Type{Type_of[B]()}},
+		output_types: [][]Type{[]Type{Type_of[R]()}},
+		exec: func(in []Value)([]Value, error) {
+			var a A
+			var b B
+			var r R
+			var err error
+
+			a, err = Get[A](in[0])
+			if err != nil {
+				return nil, err
+			}
+			b, err = Get[B](in[1])
+			if err != nil {
+				return nil, err
+			}
+			r, err = fn(a, b)
+			if err != nil {
+				return nil, err
+			}
+			return []Value{Value_of(r)}, nil
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "testing"
+
+func Test_generic(t *testing.T) {
+	var e *Expr
+	var err error
+	var v []Value
+	var f float64
+	var s string
+	var add Elt
+	var div Elt
+	var length Elt
+
+	if Type_of[float64]() != Type_of[float64]() {
+		t.Errorf("Expect same type instance")
+	}
+	if Type_string(Type_of[float64]()) != "float64" {
+		t.Errorf("Expect \"float64\", got %q", Type_string(Type_of[float64]()))
+	}
+	if Type_of[[]string]().Name() != "[]string" {
+		t.Errorf("Expect \"[]string\", got %q", Type_of[[]string]().Name())
+	}
+
+	add = Binary("+", 1, Associativity_left, func(a float64, b float64)(float64, error) {
+		return a + b, nil
+	})
+	div = Binary("/", 2, Associativity_left, func(a float64, b float64)(float64, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	})
+	length = Unary("len", 3, Associativity_right, func(a string)(float64, error) {
+		return float64(len(a)), nil
+	})
+	if Type_list(div.Input_types()) != "float64, float64" || Type_list(div.Output_types()) != "float64" {
+		t.Errorf("Unexpected signature %s", Signature(div))
+	}
+
+	e = New(nil)
+	e.Append(length)
+	e.Append(Constant("\"abcd\"", "abcd"))
+	e.Append(add)
+	e.Append(Constant("1", 1.0))
+	e.Append(div)
+	e.Append(Constant("2", 2.0))
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	f, err = Get[float64](v[0])
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if f != 4.5 {
+		t.Errorf("Expect 4.5, got %f", f)
+	}
+	_, err = Get[string](v[0])
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = Get[string](nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = Get[float64](value_float64(1))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* type mismatch detected by Finalize */
+	e = New(nil)
+	e.Append(Constant("\"a\"", "a"))
+	e.Append(add)
+	e.Append(Constant("1", 1.0))
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* errors of the function are returned */
+	e = New(nil)
+	e.Append(Constant("1", 1.0))
+	e.Append(div)
+	e.Append(Constant("0", 0.0))
+	e.Finalize()
+	_, err = e.Execute(context.Background(), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	s, err = Get[string](Value_of("x"))
+	if err != nil || s != "x" {
+		t.Errorf("Expect \"x\"")
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/thierry-f-78/go-shuntingyard
 
-go 1.16
+go 1.18
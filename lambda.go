@@ -0,0 +1,199 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strings"
+
+// Create type func<p1, ..., pn, r> of the functions which consume values
+// of types p1 to pn and return a value of type r. The function types
+// are contravariant in their parameters.
+func Func_type(params []Type, result Type)(*Param_type) {
+	return New_param_type("func", append(append([]Type(nil), params...), result)...)
+}
+
+/* key of the arguments of the lambda call in the context */
+type lambda_key struct {
+	l *Lambda
+}
+
+// Anonymous function built from a nested expression. The body is built
+// with Append or Push like any expression, the parameters are value
+// elements returned by Param. The lambda is a value element: it pushes
+// a Lambda_value which keeps the context of its evaluation, so the
+// variables of the body are read from the context of the creation of
+// the lambda, and not from the context of the call.
+type Lambda struct {
+	body *Expr
+	names []string
+	params []Type
+	output_types [][]Type
+}
+
+func New_lambda()(*Lambda) {
+	return &Lambda{
+		body: New(nil),
+	}
+}
+
+// Declare the next parameter and return the value element which reads it
+// in the body.
+func (l *Lambda)Param(name string, t Type)(Elt) {
+	l.names = append(l.names, name)
+	l.params = append(l.params, t)
+	return &lambda_param{
+		l: l,
+		index: len(l.params) - 1,
+		name: name,
+		output_types: [][]Type{[]Type{t}},
+	}
+}
+
+// Return the expression used as body
+func (l *Lambda)Body()(*Expr) {
+	return l.body
+}
+
+// Append element to the body, like Expr.Append
+func (l *Lambda)Append(elt Elt)(error) {
+	return l.body.Append(elt)
+}
+
+// Push element to the body, like Expr.Push
+func (l *Lambda)Push(elt Elt)(error) {
+	return l.body.Push(elt)
+}
+
+// Finalize the body, which must return one value of one type. The
+// lambda must be finalized before being used in an expression.
+func (l *Lambda)Finalize()(error) {
+	var out [][]Type
+	var err error
+
+	err = l.body.Finalize()
+	if err != nil {
+		return err
+	}
+	out = l.body.Output_types()
+	if len(out) != 1 || len(out[0]) != 1 {
+		return fmt.Errorf("Lambda %q must return one value of one type, got %s",
+		                  l.String(), Type_list(out))
+	}
+	l.output_types = [][]Type{[]Type{Func_type(l.params, out[0][0])}}
+	return nil
+}
+
+// Return the type func<...> of the lambda, nil if it is not finalized
+func (l *Lambda)Type()(Type) {
+	if l.output_types == nil {
+		return nil
+	}
+	return l.output_types[0][0]
+}
+
+func (l *Lambda)Precedence()(int) {
+	return 0
+}
+func (l *Lambda)Associativity()(int) {
+	return 0
+}
+func (l *Lambda)Kind()(int) {
+	return Kind_value
+}
+func (l *Lambda)String()(string) {
+	return "(" + strings.Join(l.names, ", ") + ") -> " + l.body.String()
+}
+func (l *Lambda)Input_types()([][]Type) {
+	return nil
+}
+func (l *Lambda)Output_types()([][]Type) {
+	return l.output_types
+}
+func (l *Lambda)Execute(ctx context.Context, in []Value)([]Value, error) {
+	if l.output_types == nil {
+		return nil, fmt.Errorf("Lambda %q is not finalized", l.String())
+	}
+	return []Value{&Lambda_value{l: l, env: ctx}}, nil
+}
+
+/* key of the parameter index of the lambda */
+type lambda_param_key struct {
+	l *Lambda
+	index int
+}
+
+/* value element reading parameter of the lambda */
+type lambda_param struct {
+	l *Lambda
+	index int
+	name string
+	output_types [][]Type
+}
+
+func (p *lambda_param)Precedence()(int) {
+	return 0
+}
+func (p *lambda_param)Associativity()(int) {
+	return 0
+}
+func (p *lambda_param)Kind()(int) {
+	return Kind_value
+}
+func (p *lambda_param)String()(string) {
+	return p.name
+}
+func (p *lambda_param)Input_types()([][]Type) {
+	return nil
+}
+func (p *lambda_param)Output_types()([][]Type) {
+	return p.output_types
+}
+func (p *lambda_param)Variable()(interface{}) {
+	return lambda_param_key{l: p.l, index: p.index}
+}
+func (p *lambda_param)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var args []Value
+	var ok bool
+
+	args, ok = ctx.Value(lambda_key{l: p.l}).([]Value)
+	if !ok {
+		return nil, fmt.Errorf("Parameter %q used outside of its lambda", p.name)
+	}
+	return args[p.index:p.index + 1], nil
+}
+
+// Value of a lambda, it is called by the higher-order operators
+type Lambda_value struct {
+	l *Lambda
+	env context.Context
+}
+
+func (v *Lambda_value)Descr()(string) {
+	return v.l.String()
+}
+
+func (v *Lambda_value)Type()(Type) {
+	return v.l.Type()
+}
+
+// Call the lambda with the arguments args, and return its result
+func (v *Lambda_value)Call(args ...Value)(Value, error) {
+	var out []Value
+	var i int
+	var err error
+
+	if len(args) != len(v.l.params) {
+		return nil, fmt.Errorf("Lambda %q needs %d arguments, got %d",
+		                       v.l.String(), len(v.l.params), len(args))
+	}
+	for i = range args {
+		if args[i] == nil || !Is_assignable(args[i].Type(), v.l.params[i]) {
+			return nil, fmt.Errorf("Lambda %q needs %s as argument #%d, got %s",
+			                       v.l.String(), Type_string(v.l.params[i]), i, value_desc(args[i]))
+		}
+	}
+	out, err = v.l.body.Execute(context.WithValue(v.env, lambda_key{l: v.l}, args), nil)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
@@ -0,0 +1,113 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+/* variable "offset" reading int64 from the context */
+type lambda_var_key struct {}
+
+type lambda_var struct {}
+
+func (v *lambda_var)Precedence()(int) { return 0 }
+func (v *lambda_var)Associativity()(int) { return 0 }
+func (v *lambda_var)Kind()(int) { return Kind_value }
+func (v *lambda_var)String()(string) { return "offset" }
+func (v *lambda_var)Input_types()([][]Type) { return nil }
+func (v *lambda_var)Output_types()([][]Type) { return [][]Type{[]Type{Type_of[int64]()}} }
+func (v *lambda_var)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{ctx.Value(lambda_var_key{}).(Value)}, nil
+}
+
+func Test_lambda(t *testing.T) {
+	var l *Lambda
+	var x Elt
+	var e *Expr
+	var v []Value
+	var out Value
+	var f *Lambda_value
+	var ctx context.Context
+	var err error
+	var mul Elt
+	var add Elt
+	var offset Elt
+	var elt Elt
+
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	offset = &lambda_var{}
+
+	/* (x) -> x * 2 + offset */
+	l = New_lambda()
+	x = l.Param("x", Type_of[int64]())
+	for _, elt = range []Elt{x, mul, Constant("2", int64(2)), add, offset} {
+		err = l.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	if l.Type() != nil {
+		t.Errorf("Expect nil type before Finalize")
+	}
+	err = l.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if l.String() != "(x) -> x * 2 + offset" || l.Type().Name() != "func<int64, int64>" {
+		t.Errorf("Unexpected lambda %q of type %s", l.String(), l.Type().Name())
+	}
+
+	/* the lambda is a value of an expression, it captures the context
+	 * of its evaluation.
+	 */
+	e = New(nil)
+	e.Append(l)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	ctx = context.WithValue(With_checked(context.Background()), lambda_var_key{}, Value_of(int64(10)))
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	f = v[0].(*Lambda_value)
+	out, err = f.Call(Value_of(int64(3)))
+	if err != nil || out.Descr() != "16" {
+		t.Errorf("Expect 16, got %v %v", out, err)
+	}
+	_, err = f.Call(Value_of("a"))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = f.Call()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* parameter outside of the lambda */
+	_, err = x.Execute(context.Background(), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* the lambda must return one type */
+	l = New_lambda()
+	l.Append(l.Param("a", Type_of[int64]()))
+	err = l.Append(l.Param("b", Type_of[int64]()))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	l = New_lambda()
+	l.Push(l.Param("a", Type_of[int64]()))
+	l.Push(l.Param("b", Type_of[int64]()))
+	err = l.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* function types are contravariant in their parameters */
+	if !Is_assignable(Func_type([]Type{type_number}, type_int), Func_type([]Type{type_int}, type_number)) ||
+	   Is_assignable(Func_type([]Type{type_int}, type_int), Func_type([]Type{type_number}, type_int)) {
+		t.Errorf("Unexpected variance of function types")
+	}
+}
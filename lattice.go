@@ -0,0 +1,103 @@
+package shuntingyard
+
+import "fmt"
+import "sort"
+
+// Type hierarchy. Each type is declared with its direct supertypes. The
+// lattice is used by the types implementing Assignable, and it computes
+// the common supertype of alternatives.
+type Lattice struct {
+	parents map[Type][]Type
+}
+
+func New_lattice()(*Lattice) {
+	return &Lattice{
+		parents: make(map[Type][]Type),
+	}
+}
+
+// Declare the type t with its direct supertypes
+func (l *Lattice)Add(t Type, parents ...Type) {
+	l.parents[t] = append(l.parents[t], parents...)
+}
+
+/* return all the supertypes of t, including t */
+func (l *Lattice)ancestors(t Type)(map[Type]bool) {
+	var out map[Type]bool
+	var todo []Type
+	var p Type
+
+	out = make(map[Type]bool)
+	todo = append(todo, t)
+	for len(todo) > 0 {
+		t = todo[len(todo) - 1]
+		todo = todo[:len(todo) - 1]
+		if out[t] {
+			continue
+		}
+		out[t] = true
+		for _, p = range l.parents[t] {
+			todo = append(todo, p)
+		}
+	}
+	return out
+}
+
+// Return true if t is super or one of its subtypes
+func (l *Lattice)Is_subtype(t Type, super Type)(bool) {
+	return l.ancestors(t)[super]
+}
+
+// Return the least common supertype of the types. It returns an error
+// if the types have no common supertype or if many common supertypes
+// are candidates.
+func (l *Lattice)Common_supertype(types []Type)(Type, error) {
+	var common map[Type]bool
+	var ancestors map[Type]bool
+	var t Type
+	var c Type
+	var other Type
+	var minimal []Type
+	var is_minimal bool
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("No type")
+	}
+
+	/* intersection of the supertypes of all the types */
+	common = l.ancestors(types[0])
+	for _, t = range types[1:] {
+		ancestors = l.ancestors(t)
+		for c = range common {
+			if !ancestors[c] {
+				delete(common, c)
+			}
+		}
+	}
+
+	/* keep the common supertypes which have no common subtype */
+	for c = range common {
+		is_minimal = true
+		for other = range common {
+			if other != c && l.Is_subtype(other, c) {
+				is_minimal = false
+				break
+			}
+		}
+		if is_minimal {
+			minimal = append(minimal, c)
+		}
+	}
+
+	switch len(minimal) {
+	case 0:
+		return nil, fmt.Errorf("No common supertype for %s", Type_desc(types))
+	case 1:
+		return minimal[0], nil
+	}
+	sort.Slice(minimal, func(i int, j int)(bool) {
+		return minimal[i].Name() < minimal[j].Name()
+	})
+	return nil, fmt.Errorf("Ambiguous common supertype for %s, candidates are %s",
+	                       Type_desc(types), Type_desc(minimal))
+}
@@ -0,0 +1,142 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+/* types of the test hierarchy:
+ *          any
+ *        /     \
+ *    number   comparable
+ *    /    \   /
+ *  real    int
+ */
+type lattice_type string
+
+var test_lattice *Lattice = New_lattice()
+
+var type_any lattice_type = "any"
+var type_number lattice_type = "number"
+var type_comparable lattice_type = "comparable"
+var type_real lattice_type = "real"
+var type_int lattice_type = "int"
+
+func init() {
+	test_lattice.Add(type_number, type_any)
+	test_lattice.Add(type_comparable, type_any)
+	test_lattice.Add(type_real, type_number)
+	test_lattice.Add(type_int, type_number, type_comparable)
+}
+
+func (t lattice_type)Name()(string) {
+	return string(t)
+}
+
+func (t lattice_type)Assignable_to(r Type)(bool) {
+	return test_lattice.Is_subtype(t, r)
+}
+
+/* type which accepts all the types */
+type accept_all_type struct {}
+
+func (t *accept_all_type)Name()(string) {
+	return "all"
+}
+
+func (t *accept_all_type)Accepts(r Type)(bool) {
+	return true
+}
+
+type lattice_value int
+
+func (v lattice_value)Descr()(string) {
+	return "1"
+}
+
+func (v lattice_value)Type()(Type) {
+	return type_int
+}
+
+func Test_lattice(t *testing.T) {
+	var typ Type
+	var err error
+	var e *Expr
+	var l *Lattice
+
+	if !Has_compat([]Type{type_int}, []Type{type_number}) {
+		t.Errorf("Expect int assignable to number")
+	}
+	if !Has_compat([]Type{type_int, type_real}, []Type{type_number}) {
+		t.Errorf("Expect int|real assignable to number")
+	}
+	if Has_compat([]Type{type_number}, []Type{type_int}) {
+		t.Errorf("Expect number not assignable to int")
+	}
+	if Has_compat([]Type{type_real}, []Type{type_comparable}) {
+		t.Errorf("Expect real not assignable to comparable")
+	}
+	if !Has_compat([]Type{type_bool}, []Type{&accept_all_type{}}) {
+		t.Errorf("Expect bool accepted by all")
+	}
+
+	typ, err = test_lattice.Common_supertype([]Type{type_int, type_real})
+	if err != nil || typ != type_number {
+		t.Errorf("Expect number, got %v, %v", typ, err)
+	}
+	typ, err = test_lattice.Common_supertype([]Type{type_int})
+	if err != nil || typ != type_int {
+		t.Errorf("Expect int, got %v, %v", typ, err)
+	}
+	typ, err = test_lattice.Common_supertype([]Type{type_int, type_comparable})
+	if err != nil || typ != type_comparable {
+		t.Errorf("Expect comparable, got %v, %v", typ, err)
+	}
+	typ, err = test_lattice.Common_supertype([]Type{type_real, type_comparable})
+	if err != nil || typ != type_any {
+		t.Errorf("Expect any, got %v, %v", typ, err)
+	}
+	_, err = test_lattice.Common_supertype([]Type{type_int, type_bool})
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = test_lattice.Common_supertype(nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* ambiguous: int has two minimal supertypes. The shared lattice is
+	 * not modified, the hierarchy is rebuilt in a local one.
+	 */
+	l = New_lattice()
+	l.Add(type_number, type_any)
+	l.Add(type_comparable, type_any)
+	l.Add(type_real, type_number)
+	l.Add(type_int, type_number, type_comparable)
+	l.Add(lattice_type("small"), type_number, type_comparable)
+	_, err = l.Common_supertype([]Type{type_int, lattice_type("small")})
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if err.Error() != "Ambiguous common supertype for int|small, candidates are comparable|number" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	/* subtypes are accepted by Finalize and by checked execution */
+	e = New(nil)
+	e.Append(&test_fn{
+		test: test{kind: Kind_value, symbol: "1", output_types: [][]Type{[]Type{type_int}}},
+		fn: func(vs []Value)([]Value, error) { return []Value{lattice_value(1)}, nil },
+	})
+	e.Append(&test_fn{
+		test: test{kind: Kind_operator, symbol: "abs", precedence: 3,
+		           input_types: [][]Type{[]Type{type_number}},
+		           output_types: [][]Type{[]Type{type_number}}},
+		fn: func(vs []Value)([]Value, error) { return vs, nil },
+	})
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	_, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
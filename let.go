@@ -0,0 +1,165 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+
+// Precedence of the operator In of the let-bindings, lower than all the
+// usual operators
+const Precedence_let = -1
+
+// Let binds a name to the value of a sub-expression. The binding
+// "let name = expr in body" is built by appending expr, the operator
+// returned by In and the body, where the elements returned by Ref
+// designate the value of expr. Finalize checks expr once and uses its
+// type for each reference, Execute computes it once and the references
+// copy it from the stack.
+type Let struct {
+	name string
+	in *let_in
+}
+
+func New_let(name string)(*Let) {
+	var l *Let
+
+	l = &Let{
+		name: name,
+	}
+	l.in = &let_in{l: l}
+	return l
+}
+
+// Return the name of the binding
+func (l *Let)Name()(string) {
+	return l.name
+}
+
+// Return the right associative operator which binds its left operand
+// to the name in its right operand, and returns its right operand.
+// Its precedence is Precedence_let.
+func (l *Let)In()(Elt) {
+	return l.in
+}
+
+// Return value element referencing the bound value. It is valid only
+// in the right operand of In.
+func (l *Let)Ref()(Elt) {
+	return &let_ref{l: l}
+}
+
+var let_bound *Type_var = New_type_var("bound")
+var let_body *Type_var = New_type_var("body")
+
+/* operator "expr in body" */
+type let_in struct {
+	l *Let
+}
+
+func (i *let_in)Precedence()(int) {
+	return Precedence_let
+}
+func (i *let_in)Associativity()(int) {
+	return Associativity_right
+}
+func (i *let_in)Kind()(int) {
+	return Kind_operator
+}
+func (i *let_in)String()(string) {
+	return "as " + i.l.name + " in"
+}
+func (i *let_in)Input_types()([][]Type) {
+	return [][]Type{[]Type{let_bound}, []Type{let_body}}
+}
+func (i *let_in)Output_types()([][]Type) {
+	return [][]Type{[]Type{let_body}}
+}
+func (i *let_in)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return in[1:2], nil
+}
+
+/* reference to the bound value, Finalize replaces it by a copy of
+ * the stack entry of the value.
+ */
+type let_ref struct {
+	l *Let
+}
+
+func (r *let_ref)Precedence()(int) {
+	return 0
+}
+func (r *let_ref)Associativity()(int) {
+	return 0
+}
+func (r *let_ref)Kind()(int) {
+	return Kind_value
+}
+func (r *let_ref)String()(string) {
+	return r.l.name
+}
+func (r *let_ref)Input_types()([][]Type) {
+	return nil
+}
+func (r *let_ref)Output_types()([][]Type) {
+	return [][]Type{[]Type{}}
+}
+func (r *let_ref)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return nil, fmt.Errorf("%q is not bound", r.l.name)
+}
+
+/* resolve the stack entry of the let references and of the macro
+ * parameters. the elements inlined from a macro are already resolved.
+ */
+func (e *Expr)bind_locals()(error) {
+	var shape *rpn_shape
+	var bound map[*Let]bool
+	var pos int
+	var k int
+	var ec *elt_cache
+	var in *let_in
+	var ref *let_ref
+	var param *macro_param
+	var start int
+	var ok bool
+
+	shape = new_rpn_shape(e.rpn, len(e.input_types))
+	bound = make(map[*Let]bool)
+	for pos, ec = range e.rpn {
+		in, ok = ec.elt.(*let_in)
+		if !ok || ec.macro != "" {
+			continue
+		}
+		if bound[in.l] {
+			return fmt.Errorf("Expression error, %q is bound twice", in.l.name)
+		}
+		bound[in.l] = true
+		if !shape.distinct(pos, 1) {
+			return fmt.Errorf("Inconsistent expression, %q needs a value and a body", ec.elt.String())
+		}
+
+		/* the bound value is the entry below the body */
+		start = shape.starts[pos][1]
+		for k = start; k < pos; k++ {
+			ref, ok = e.rpn[k].elt.(*let_ref)
+			if ok && ref.l == in.l && e.rpn[k].macro == "" {
+				e.rpn[k].local = true
+				e.rpn[k].slot = shape.depth[start] - 1
+			}
+		}
+	}
+
+	for _, ec = range e.rpn {
+		ref, ok = ec.elt.(*let_ref)
+		if ok && !ec.local {
+			return fmt.Errorf("Expression error, %q is used outside of its binding", ref.l.name)
+		}
+		param, ok = ec.elt.(*macro_param)
+		if ok && ec.macro == "" {
+			if param.m != e.macro {
+				return fmt.Errorf("Expression error, parameter %q is used outside of macro %q",
+				                  param.name, param.m.name)
+			}
+			ec.local = true
+			ec.slot = param.index
+		}
+	}
+	return nil
+}
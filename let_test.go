@@ -0,0 +1,101 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+/* value element counting its executions */
+type count_elt struct {
+	calls int
+}
+
+func (c *count_elt)Precedence()(int) { return 0 }
+func (c *count_elt)Associativity()(int) { return 0 }
+func (c *count_elt)Kind()(int) { return Kind_value }
+func (c *count_elt)String()(string) { return "count" }
+func (c *count_elt)Input_types()([][]Type) { return nil }
+func (c *count_elt)Output_types()([][]Type) { return [][]Type{[]Type{Type_of[int64]()}} }
+func (c *count_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	c.calls++
+	return []Value{Value_of(int64(2))}, nil
+}
+
+func Test_let(t *testing.T) {
+	var add Elt
+	var mul Elt
+	var three Elt
+	var count *count_elt
+	var x *Let
+	var y *Let
+	var e *Expr
+	var v []Value
+	var err error
+	var elt Elt
+	var elts []Elt
+	var c struct {
+		elts []Elt
+		expect string
+	}
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	three = Constant("3", int64(3))
+	count = &count_elt{}
+	x = New_let("x")
+	y = New_let("y")
+
+	for _, c = range []struct {
+		elts []Elt
+		expect string
+	}{
+		/* count + 3 as x in x * x + x */
+		{[]Elt{count, add, three, x.In(), x.Ref(), mul, x.Ref(), add, x.Ref()}, "30"},
+		/* (count + 3 as x in x * x) + 3 */
+		{[]Elt{op_open, count, add, three, x.In(), x.Ref(), mul, x.Ref(), op_close, add, three}, "28"},
+		/* count as x in 3 as y in x * y + x */
+		{[]Elt{count, x.In(), three, y.In(), x.Ref(), mul, y.Ref(), add, x.Ref()}, "8"},
+	} {
+		count.calls = 0
+		e = New(nil)
+		for _, elt = range c.elts {
+			err = e.Append(elt)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+		}
+		err = e.Finalize()
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+			continue
+		}
+		if Type_list(e.Output_types()) != "int64" {
+			t.Errorf("Expect int64 for %q, got %s", e.String(), Type_list(e.Output_types()))
+		}
+		v, err = e.Execute(With_checked(context.Background()), nil)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+		} else if v[0].Descr() != c.expect || count.calls != 1 {
+			t.Errorf("Expect %s computing count once for %q, got %s computing count %d times",
+			         c.expect, e.String(), v[0].Descr(), count.calls)
+		}
+	}
+	if e.String() != "count as x in 3 as y in x * y + x" {
+		t.Errorf("Unexpected name %q", e.String())
+	}
+
+	/* errors */
+	for _, elts = range [][]Elt{
+		{x.Ref(), add, three},
+		{three, x.In(), three, add, y.Ref()},
+		{three, x.In(), three, x.In(), x.Ref()},
+		{x.In(), three},
+	} {
+		e = New(nil)
+		for _, elt = range elts {
+			e.Append(elt)
+		}
+		err = e.Finalize()
+		if err == nil {
+			t.Errorf("Expect error for %q, got no error", e.String())
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strings"
+
+// Precedence of the references to the macros with parameters. They are
+// prefix operators, their operands are usually groups: "in_range (x) (1) (10)"
+const Precedence_macro = Precedence_member - 1
+
+// Library of named sub-expressions, called macros. The macros are
+// referenced by name in other macros and in expressions, they are
+// executed as nested expressions or inlined in the calling expression.
+type Library struct {
+	macros map[string]*Macro
+	order []string
+	done bool
+}
+
+func New_library()(*Library) {
+	return &Library{
+		macros: make(map[string]*Macro),
+	}
+}
+
+// Named expression of a library. The parameters are declared with Param,
+// the body is built with Append or Push.
+type Macro struct {
+	lib *Library
+	name string
+	names []string
+	body *Expr
+	inline bool
+	output_types [][]Type
+	done bool
+}
+
+// Define macro. The macros referencing it can be built before its body,
+// but after the declaration of its parameters.
+func (l *Library)Define(name string)(*Macro, error) {
+	var m *Macro
+	var ok bool
+
+	if l.done {
+		return nil, fmt.Errorf("Library already finalized")
+	}
+	_, ok = l.macros[name]
+	if ok {
+		return nil, fmt.Errorf("Macro %q already defined", name)
+	}
+	m = &Macro{
+		lib: l,
+		name: name,
+		body: New(nil),
+	}
+	m.body.Set_name(name)
+	m.body.macro = m
+	l.macros[name] = m
+	l.order = append(l.order, name)
+	return m, nil
+}
+
+// Return the macro named name
+func (l *Library)Get(name string)(*Macro, bool) {
+	var m *Macro
+	var ok bool
+
+	m, ok = l.macros[name]
+	return m, ok
+}
+
+// Return the names of the macros in definition order
+func (l *Library)Names()([]string) {
+	return append([]string(nil), l.order...)
+}
+
+// Return element referencing the macro name. The element is a value if
+// the macro has no parameter, otherwise a prefix operator with the
+// precedence Precedence_macro.
+func (l *Library)Ref(name string)(Elt, error) {
+	var m *Macro
+	var ok bool
+
+	m, ok = l.macros[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown macro %q", name)
+	}
+	return &macro_ref{m: m}, nil
+}
+
+/* return the macros referenced by the body of m */
+func (m *Macro)deps()([]*Macro) {
+	var out []*Macro
+	var ec *elt_cache
+	var ref *macro_ref
+	var ok bool
+
+	for _, ec = range append(append([]*elt_cache(nil), m.body.rpn...), m.body.precedence_stack...) {
+		ref, ok = ec.elt.(*macro_ref)
+		if ok {
+			out = append(out, ref.m)
+		}
+	}
+	return out
+}
+
+/* finalize m after the macros it references. path is the chain of
+ * the macros being finalized, used to report cycles.
+ */
+func (l *Library)finalize(m *Macro, path []string)(error) {
+	var name string
+	var d *Macro
+	var err error
+
+	if m.done {
+		return nil
+	}
+	for _, name = range path {
+		if name == m.name {
+			return fmt.Errorf("Macro cycle %s", strings.Join(append(path, m.name), " -> "))
+		}
+	}
+	for _, d = range m.deps() {
+		if d.lib != l {
+			return fmt.Errorf("Macro %q references %q of another library", m.name, d.name)
+		}
+		err = l.finalize(d, append(path, m.name))
+		if err != nil {
+			return err
+		}
+	}
+	return m.finalize()
+}
+
+// Finalize all the macros, each one after the macros it references.
+// Returns an error if the references are cyclic.
+func (l *Library)Finalize()(error) {
+	var name string
+	var err error
+
+	if l.done {
+		return fmt.Errorf("Library already finalized")
+	}
+	for _, name = range l.order {
+		err = l.finalize(l.macros[name], nil)
+		if err != nil {
+			return err
+		}
+	}
+	l.done = true
+	return nil
+}
+
+// Declare the next parameter and return the value element which reads it
+// in the body. The parameters are the inputs of the body.
+func (m *Macro)Param(name string, types []Type)(Elt) {
+	m.names = append(m.names, name)
+	m.body.input_types = append(m.body.input_types, types)
+	return &macro_param{
+		m: m,
+		index: len(m.names) - 1,
+		name: name,
+		output_types: [][]Type{types},
+	}
+}
+
+// Append element to the body, like Expr.Append
+func (m *Macro)Append(elt Elt)(error) {
+	return m.body.Append(elt)
+}
+
+// Push element to the body, like Expr.Push
+func (m *Macro)Push(elt Elt)(error) {
+	return m.body.Push(elt)
+}
+
+// Inlined macros are copied in the rpn of the calling expressions,
+// the others are executed as nested expressions.
+func (m *Macro)Set_inline(inline bool) {
+	m.inline = inline
+}
+
+// Return the name of the macro
+func (m *Macro)Name()(string) {
+	return m.name
+}
+
+// Return the body of the macro
+func (m *Macro)Body()(*Expr) {
+	return m.body
+}
+
+/* finalize the body, which must leave its parameters and one result */
+func (m *Macro)finalize()(error) {
+	var out [][]Type
+	var err error
+
+	err = m.body.Finalize()
+	if err != nil {
+		return fmt.Errorf("Macro %q: %s", m.name, err.Error())
+	}
+	out = m.body.Output_types()
+	if len(out) != len(m.names) + 1 {
+		return fmt.Errorf("Macro %q must return one value, got %s", m.name, Type_list(out))
+	}
+	m.output_types = out[len(m.names):]
+	m.done = true
+	return nil
+}
+
+/* value element reading a parameter of the macro */
+type macro_param struct {
+	m *Macro
+	index int
+	name string
+	output_types [][]Type
+}
+
+func (p *macro_param)Precedence()(int) {
+	return 0
+}
+func (p *macro_param)Associativity()(int) {
+	return 0
+}
+func (p *macro_param)Kind()(int) {
+	return Kind_value
+}
+func (p *macro_param)String()(string) {
+	return p.name
+}
+func (p *macro_param)Input_types()([][]Type) {
+	return nil
+}
+func (p *macro_param)Output_types()([][]Type) {
+	return p.output_types
+}
+func (p *macro_param)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return nil, fmt.Errorf("Parameter %q is not bound", p.name)
+}
+
+/* reference to a macro */
+type macro_ref struct {
+	m *Macro
+}
+
+func (r *macro_ref)Precedence()(int) {
+	if len(r.m.names) == 0 {
+		return 0
+	}
+	return Precedence_macro
+}
+func (r *macro_ref)Associativity()(int) {
+	return Associativity_right
+}
+func (r *macro_ref)Kind()(int) {
+	if len(r.m.names) == 0 {
+		return Kind_value
+	}
+	return Kind_operator
+}
+func (r *macro_ref)String()(string) {
+	return r.m.name
+}
+func (r *macro_ref)Input_types()([][]Type) {
+	return r.m.body.input_types
+}
+func (r *macro_ref)Output_types()([][]Type) {
+	return r.m.output_types
+}
+func (r *macro_ref)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var out []Value
+	var err error
+
+	if !r.m.done {
+		return nil, fmt.Errorf("Macro %q is not finalized", r.m.name)
+	}
+	out, err = r.m.body.Execute(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out[len(out) - 1:], nil
+}
+
+/* element ending an inlined macro, it drops the parameters below the result */
+type macro_return struct {
+	m *Macro
+}
+
+func (r *macro_return)Precedence()(int) {
+	return 0
+}
+func (r *macro_return)Associativity()(int) {
+	return 0
+}
+func (r *macro_return)Kind()(int) {
+	return Kind_operator
+}
+func (r *macro_return)String()(string) {
+	return r.m.name
+}
+func (r *macro_return)Input_types()([][]Type) {
+	return append(append([][]Type(nil), r.m.body.input_types...), r.m.output_types...)
+}
+func (r *macro_return)Output_types()([][]Type) {
+	return r.m.output_types
+}
+func (r *macro_return)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return in[len(in) - 1:], nil
+}
+
+/* update the references to the macros with the finalized macros, and
+ * replace the references to the inlined macros by their body.
+ */
+func (e *Expr)expand_macros()(error) {
+	var shape *rpn_shape
+	var out []*elt_cache
+	var pos int
+	var ec *elt_cache
+	var b *elt_cache
+	var c *elt_cache
+	var ref *macro_ref
+	var base int
+	var ok bool
+
+	/* the references cache the types of the macros at the time
+	 * of Append, the macros may be finalized after.
+	 */
+	for pos, ec = range e.rpn {
+		ref, ok = ec.elt.(*macro_ref)
+		if !ok {
+			continue
+		}
+		if !ref.m.done {
+			return fmt.Errorf("Macro %q is not finalized", ref.m.name)
+		}
+		c = new_elt_cache(ref)
+		c.macro = ec.macro
+		e.rpn[pos] = c
+	}
+
+	shape = new_rpn_shape(e.rpn, len(e.input_types))
+	for pos, ec = range e.rpn {
+		ref, ok = ec.elt.(*macro_ref)
+		if !ok || !ref.m.inline {
+			out = append(out, ec)
+			continue
+		}
+
+		/* the parameters are the entries below the reference */
+		base = shape.depth[pos] - len(ref.m.names)
+		if base < 0 {
+			return fmt.Errorf("Inconsistent expression, need %d entries, only %d available at symbol %q",
+			                  len(ref.m.names), shape.depth[pos], ref.m.name)
+		}
+		for _, b = range ref.m.body.rpn {
+			c = &elt_cache{}
+			*c = *b
+			if c.local {
+				c.slot += base
+			}
+			if c.macro == "" {
+				c.macro = ref.m.name
+			} else {
+				c.macro = ref.m.name + "/" + c.macro
+			}
+			out = append(out, c)
+		}
+		c = new_elt_cache(&macro_return{m: ref.m})
+		c.macro = ref.m.name
+		out = append(out, c)
+	}
+	e.rpn = out
+	return nil
+}
@@ -0,0 +1,199 @@
+package shuntingyard
+
+import "bytes"
+import "context"
+import "testing"
+
+/* build library: five = 2 + 3, double(x) = x + x, quad(x) = double (x) + double (x) */
+func test_library(t *testing.T, inline bool)(*Library) {
+	var lib *Library
+	var m *Macro
+	var x Elt
+	var double Elt
+	var add Elt
+	var d *Macro
+	var y Elt
+	var elt Elt
+	var err error
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	lib = New_library()
+
+	/* quad is defined before double, and references it */
+	m, _ = lib.Define("quad")
+	x = m.Param("x", []Type{Type_of[int64]()})
+	_, err = lib.Define("quad")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = lib.Ref("double")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	d, _ = lib.Define("double")
+	y = d.Param("y", []Type{Type_of[int64]()})
+	double, _ = lib.Ref("double")
+	for _, elt = range []Elt{double, op_open, x, op_close, add, double, op_open, x, op_close} {
+		m.Append(elt)
+	}
+	for _, elt = range []Elt{y, add, y} {
+		d.Append(elt)
+	}
+	d.Set_inline(inline)
+
+	m, _ = lib.Define("five")
+	for _, elt = range []Elt{Constant("2", int64(2)), add, Constant("3", int64(3))} {
+		m.Append(elt)
+	}
+	m.Set_inline(inline)
+
+	err = lib.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	return lib
+}
+
+func Test_macro(t *testing.T) {
+	var lib *Library
+	var e *Expr
+	var v []Value
+	var ref Elt
+	var m *Macro
+	var a *Macro
+	var b *Macro
+	var x Elt
+	var err error
+	var inline bool
+	var out bytes.Buffer
+
+	for _, inline = range []bool{false, true} {
+		lib = test_library(t, inline)
+		if len(lib.Names()) != 3 || lib.Names()[0] != "quad" {
+			t.Errorf("Unexpected names %q", lib.Names())
+		}
+
+		/* quad (five) + 1 */
+		e = New(nil)
+		ref, _ = lib.Ref("quad")
+		e.Append(ref)
+		e.Append(op_open)
+		ref, _ = lib.Ref("five")
+		e.Append(ref)
+		e.Append(op_close)
+		e.Append(Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil }))
+		e.Append(Constant("1", int64(1)))
+		err = e.Finalize()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+		v, err = e.Execute(With_checked(context.Background()), nil)
+		if err != nil || v[0].Descr() != "21" {
+			t.Errorf("Expect 21, got %v %v", v, err)
+		}
+
+		out.Reset()
+		e.Fdump(&out)
+		if inline && out.String() != "[quad ( five ) + 1]:\n" +
+		                             "|   2 <five>\n|   3 <five>\n|   + <five>\n|   five <five>\n" +
+		                             "|   [quad]:\n" +
+		                             "|   |   x\n|   |   y <double>\n|   |   y <double>\n|   |   + <double>\n|   |   double <double>\n" +
+		                             "|   |   x\n|   |   y <double>\n|   |   y <double>\n|   |   + <double>\n|   |   double <double>\n" +
+		                             "|   |   +\n" +
+		                             "|   1\n|   +\n" {
+			t.Errorf("Unexpected dump:\n%s", out.String())
+		}
+		if !inline && out.String() != "[quad ( five ) + 1]:\n" +
+		                              "|   [five]:\n|   |   2\n|   |   3\n|   |   +\n" +
+		                              "|   [quad]:\n" +
+		                              "|   |   x\n|   |   [double]:\n|   |   |   y\n|   |   |   y\n|   |   |   +\n" +
+		                              "|   |   x\n|   |   [double]:\n|   |   |   y\n|   |   |   y\n|   |   |   +\n" +
+		                              "|   |   +\n" +
+		                              "|   1\n|   +\n" {
+			t.Errorf("Unexpected dump:\n%s", out.String())
+		}
+	}
+
+	/* cycle */
+	lib = New_library()
+	a, _ = lib.Define("a")
+	b, _ = lib.Define("b")
+	ref, _ = lib.Ref("b")
+	a.Append(ref)
+	ref, _ = lib.Ref("a")
+	b.Append(ref)
+	err = lib.Finalize()
+	if err == nil || err.Error() != "Macro cycle a -> b -> a" {
+		t.Errorf("Expect cycle error, got %v", err)
+	}
+
+	/* parameter used outside of its macro */
+	lib = New_library()
+	m, _ = lib.Define("m")
+	x = m.Param("x", []Type{Type_of[int64]()})
+	m.Append(x)
+	lib.Finalize()
+	e = New(nil)
+	e.Append(x)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* the macro must return one value */
+	lib = New_library()
+	m, _ = lib.Define("m")
+	m.Push(Constant("1", int64(1)))
+	m.Push(Constant("2", int64(2)))
+	err = lib.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+}
+
+func Test_macro_conversions(t *testing.T) {
+	var conv *Conversions
+	var lib *Library
+	var m *Macro
+	var x Elt
+	var ref Elt
+	var e *Expr
+	var v []Value
+	var inline bool
+	var elt Elt
+	var err error
+
+	conv = New_conversions()
+	conv.Add(Unary("int_to_float", 0, Associativity_right, func(a int64)(float64, error) { return float64(a), nil }), 1)
+
+	/* plus_half(x) = x + 0.5, x is converted to float64 */
+	for _, inline = range []bool{false, true} {
+		lib = New_library()
+		m, _ = lib.Define("plus_half")
+		x = m.Param("x", []Type{Type_of[int64]()})
+		m.Body().Set_conversions(conv)
+		m.Append(x)
+		m.Append(Binary("+", 1, Associativity_left, func(a float64, b float64)(float64, error) { return a + b, nil }))
+		m.Append(Constant("0.5", float64(0.5)))
+		m.Set_inline(inline)
+		err = lib.Finalize()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+
+		ref, _ = lib.Ref("plus_half")
+		e = New(nil)
+		for _, elt = range []Elt{ref, op_open, Constant("2", int64(2)), op_close} {
+			e.Append(elt)
+		}
+		err = e.Finalize()
+		if err != nil {
+			t.Errorf("Unexpected error with inline %v: %s", inline, err.Error())
+			continue
+		}
+		v, err = e.Execute(With_checked(context.Background()), nil)
+		if err != nil || v[0].Descr() != "2.5" {
+			t.Errorf("Expect 2.5 with inline %v, got %v, %v", inline, v, err)
+		}
+	}
+}
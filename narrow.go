@@ -0,0 +1,433 @@
+package shuntingyard
+
+import "context"
+import "time"
+
+/* narrowed types of variables, indexed by variable key */
+type narrowing map[interface{}][]Type
+
+/* narrowing scope of the operand index of a connective */
+type narrow_scope struct {
+	owner *elt_cache
+	index int
+	// the narrowing applies when the condition is true
+	when bool
+	facts narrowing
+	open bool
+}
+
+/* simulated type stack used by Finalize */
+type type_checker struct {
+	// types of each slot
+	types [][]Type
+	// key of the variable which pushed the slot, nil if the slot is computed
+	vars []interface{}
+	// narrowing known when the slot is true or false
+	when_true []narrowing
+	when_false []narrowing
+	// opened scopes, the innermost last
+	scopes []*narrow_scope
+	// scopes indexed by their first element
+	starts map[*elt_cache][]*narrow_scope
+}
+
+/* key of the local references reading the same stack entry */
+type local_key struct {
+	slot int
+}
+
+/* return the key of the element if it reads a variable, nil otherwise.
+ * the local references are variables of their stack entry.
+ */
+func var_key(ec *elt_cache)(interface{}) {
+	var v Variable
+	var ok bool
+
+	if len(ec.input_types) != 0 || len(ec.output_types) != 1 {
+		return nil
+	}
+	if ec.local {
+		return local_key{slot: ec.slot}
+	}
+	v, ok = ec.elt.(Variable)
+	if !ok {
+		return nil
+	}
+	return v.Variable()
+}
+
+/* return the alternatives of a compatible with b */
+func intersect_types(a []Type, b []Type)([]Type) {
+	var out []Type
+	var t Type
+
+	for _, t = range a {
+		if Has_compat([]Type{t}, b) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+/* return narrowing true when both a and b are true */
+func merge_narrowing(a narrowing, b narrowing)(narrowing) {
+	var out narrowing
+	var k interface{}
+	var t []Type
+	var ok bool
+
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	out = make(narrowing)
+	for k, t = range a {
+		out[k] = t
+	}
+	for k, t = range b {
+		_, ok = out[k]
+		if ok {
+			t = intersect_types(out[k], t)
+		}
+		out[k] = t
+	}
+	return out
+}
+
+/* declare the narrowing scopes of the connectives of the rpn */
+func (c *type_checker)declare_scopes(rpn []*elt_cache, inputs int) {
+	var shape *rpn_shape
+	var cn Connective
+	var ok bool
+	var pos int
+	var ec *elt_cache
+
+	c.starts = make(map[*elt_cache][]*narrow_scope)
+	shape = new_rpn_shape(rpn, inputs)
+	for pos, ec = range rpn {
+		cn, ok = ec.elt.(Connective)
+		if !ok {
+			continue
+		}
+		switch cn.Connective() {
+		case Connective_and:
+			c.declare(rpn, shape, pos, 1, true)
+		case Connective_or:
+			c.declare(rpn, shape, pos, 1, false)
+		case Connective_if:
+			c.declare(rpn, shape, pos, 1, true)
+			c.declare(rpn, shape, pos, 2, false)
+		}
+	}
+}
+
+func (c *type_checker)declare(rpn []*elt_cache, shape *rpn_shape, pos int, index int, when bool) {
+	var start int
+
+	if !shape.distinct(pos, index) {
+		return
+	}
+	start = shape.starts[pos][index]
+	c.starts[rpn[start]] = append(c.starts[rpn[start]], &narrow_scope{
+		owner: rpn[pos],
+		index: index,
+		when: when,
+	})
+}
+
+/* close the scopes owned by ec and open the scopes which start at ec */
+func (c *type_checker)enter(ec *elt_cache) {
+	var s *narrow_scope
+	var slot int
+
+	for len(c.scopes) > 0 && c.scopes[len(c.scopes) - 1].owner == ec {
+		c.scopes = c.scopes[:len(c.scopes) - 1]
+	}
+	for _, s = range c.starts[ec] {
+		if s.open {
+			continue
+		}
+		s.open = true
+
+		/* the condition is the operand #0 of the connective */
+		slot = len(c.types) - s.index
+		if slot < 0 {
+			continue
+		}
+		if s.when {
+			s.facts = c.when_true[slot]
+		} else {
+			s.facts = c.when_false[slot]
+		}
+		c.scopes = append(c.scopes, s)
+	}
+}
+
+/* return the narrowed types of the variable, or nil */
+func (c *type_checker)narrowed(key interface{})([]Type) {
+	var i int
+	var t []Type
+	var ok bool
+
+	for i = len(c.scopes) - 1; i >= 0; i-- {
+		t, ok = c.scopes[i].facts[key]
+		if ok {
+			return t
+		}
+	}
+	return nil
+}
+
+/* return the narrowing implied by the output of ec when it is true
+ * and when it is false. index is the first consumed slot.
+ */
+func (c *type_checker)facts(ec *elt_cache, index int)(narrowing, narrowing) {
+	var g Guard
+	var cn Connective
+	var ok bool
+
+	if len(ec.output_types) != 1 {
+		return nil, nil
+	}
+	g, ok = ec.elt.(Guard)
+	if ok {
+		return c.guard_facts(g, index, true), c.guard_facts(g, index, false)
+	}
+	cn, ok = ec.elt.(Connective)
+	if !ok || len(c.types) - index < 1 {
+		return nil, nil
+	}
+	switch cn.Connective() {
+	case Connective_and:
+		if len(c.types) - index == 2 {
+			return merge_narrowing(c.when_true[index], c.when_true[index + 1]), nil
+		}
+	case Connective_or:
+		if len(c.types) - index == 2 {
+			return nil, merge_narrowing(c.when_false[index], c.when_false[index + 1])
+		}
+	case Connective_not:
+		return c.when_false[index], c.when_true[index]
+	}
+	return nil, nil
+}
+
+func (c *type_checker)guard_facts(g Guard, index int, result bool)(narrowing) {
+	var out narrowing
+	var narrowed [][]Type
+	var i int
+
+	narrowed = g.Narrow(c.types[index:], result)
+	for i = range narrowed {
+		if narrowed[i] == nil || index + i >= len(c.vars) || c.vars[index + i] == nil {
+			continue
+		}
+		if out == nil {
+			out = make(narrowing)
+		}
+		out[c.vars[index + i]] = narrowed[i]
+	}
+	return out
+}
+
+/* pop n slots */
+func (c *type_checker)pop(n int) {
+	var l int
+
+	l = len(c.types) - n
+	c.types = c.types[:l]
+	c.vars = c.vars[:l]
+	c.when_true = c.when_true[:l]
+	c.when_false = c.when_false[:l]
+}
+
+/* push slot */
+func (c *type_checker)push(types []Type, key interface{}, when_true narrowing, when_false narrowing) {
+	c.types = append(c.types, types)
+	c.vars = append(c.vars, key)
+	c.when_true = append(c.when_true, when_true)
+	c.when_false = append(c.when_false, when_false)
+}
+
+/* structure of the rpn computed from the arity of the elements */
+type rpn_shape struct {
+	// position where the computation of each operand of each element
+	// starts, -1 if the operand is an input of the expression
+	starts [][]int
+	// stack depth before each element, and at the end
+	depth []int
+	rpn []*elt_cache
+}
+
+func new_rpn_shape(rpn []*elt_cache, inputs int)(*rpn_shape) {
+	var s *rpn_shape
+	var slots []int
+	var pos int
+	var ec *elt_cache
+	var n int
+	var start int
+	var i int
+
+	s = &rpn_shape{
+		starts: make([][]int, len(rpn)),
+		depth: make([]int, len(rpn) + 1),
+		rpn: rpn,
+	}
+	for i = 0; i < inputs; i++ {
+		slots = append(slots, -1)
+	}
+	for pos, ec = range rpn {
+		s.depth[pos] = len(slots)
+		n = len(ec.input_types)
+		for len(slots) < n {
+			slots = append([]int{-1}, slots...)
+		}
+		s.starts[pos] = append([]int(nil), slots[len(slots) - n:]...)
+
+		/* the outputs are computed from the first computed operand */
+		start = pos
+		for i = 0; i < n; i++ {
+			if s.starts[pos][i] >= 0 {
+				start = s.starts[pos][i]
+				break
+			}
+		}
+		slots = slots[:len(slots) - n]
+		for i = 0; i < len(ec.output_types); i++ {
+			slots = append(slots, start)
+		}
+	}
+	s.depth[len(rpn)] = len(slots)
+	return s
+}
+
+/* return the position following the operand index of the element pos */
+func (s *rpn_shape)end(pos int, index int)(int) {
+	if index + 1 < len(s.starts[pos]) {
+		return s.starts[pos][index + 1]
+	}
+	return pos
+}
+
+/* return true if the computation of the operand index (index > 0) of
+ * the element pos is a distinct part of the rpn which pushes one value
+ * and doesn't consume the values pushed before it. such operand can be
+ * skipped, and the values below it stay in place during its computation.
+ */
+func (s *rpn_shape)distinct(pos int, index int)(bool) {
+	var start int
+	var end int
+	var k int
+
+	if index < 1 || index >= len(s.starts[pos]) {
+		return false
+	}
+	start = s.starts[pos][index]
+	end = s.end(pos, index)
+	if start < 0 || start >= end || s.starts[pos][index - 1] >= start {
+		return false
+	}
+	if s.depth[end] != s.depth[start] + 1 {
+		return false
+	}
+	for k = start; k < end; k++ {
+		if s.depth[k] - len(s.rpn[k].input_types) < s.depth[start] {
+			return false
+		}
+	}
+	return true
+}
+
+/* operand which can be skipped at execution */
+type lazy_operand struct {
+	connective Connective
+	index int
+	end int
+}
+
+/* return true if the connective doesn't need the operand, the stack
+ * contains the previous operands.
+ */
+func (l *lazy_operand)skips(stack []Value)(bool) {
+	return len(stack) >= l.index && l.connective.Skip(l.index, stack[len(stack) - l.index:])
+}
+
+/* return the operands which can be skipped, indexed by their first position */
+func lazy_operands(rpn []*elt_cache, inputs int)(map[int]*lazy_operand) {
+	var out map[int]*lazy_operand
+	var shape *rpn_shape
+	var cn Connective
+	var ok bool
+	var pos int
+	var ec *elt_cache
+	var i int
+
+	shape = new_rpn_shape(rpn, inputs)
+	for pos, ec = range rpn {
+		cn, ok = ec.elt.(Connective)
+		if !ok {
+			continue
+		}
+		for i = 1; i < len(ec.input_types); i++ {
+			if !shape.distinct(pos, i) {
+				continue
+			}
+			if out == nil {
+				out = make(map[int]*lazy_operand)
+			}
+			out[shape.starts[pos][i]] = &lazy_operand{
+				connective: cn,
+				index: i,
+				end: shape.end(pos, i),
+			}
+		}
+	}
+	return out
+}
+
+/* element reported to the tracers in place of a skipped operand */
+type skipped_elt struct {}
+
+func (s *skipped_elt)Precedence()(int) {
+	return 0
+}
+func (s *skipped_elt)Associativity()(int) {
+	return 0
+}
+func (s *skipped_elt)Kind()(int) {
+	return Kind_value
+}
+func (s *skipped_elt)String()(string) {
+	return "<skipped>"
+}
+func (s *skipped_elt)Input_types()([][]Type) {
+	return nil
+}
+func (s *skipped_elt)Output_types()([][]Type) {
+	return nil
+}
+func (s *skipped_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{nil}, nil
+}
+
+var skipped Elt = &skipped_elt{}
+
+/* if the operand starting at pos is not needed by its connective, push
+ * nil in place of its value and return the position following it.
+ */
+func (e *Expr)skip_operand(opts *exec_opts, pos int, stack []Value)(int, []Value, bool) {
+	var l *lazy_operand
+	var ok bool
+
+	l, ok = e.lazy[pos]
+	if !ok || !l.skips(stack) {
+		return pos, stack, false
+	}
+	if opts.tracer != nil {
+		opts.tracer.Before(e, pos, skipped, nil)
+		opts.tracer.After(e, pos, skipped, nil, []Value{nil}, nil, time.Duration(0))
+	}
+	return l.end, append(stack, nil), true
+}
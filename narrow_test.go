@@ -0,0 +1,232 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+func Test_rpn_shape(t *testing.T) {
+	var e *Expr
+	var s *rpn_shape
+	var elt Elt
+	var err error
+
+	/* 2.3 + 2.4 * 2.5 + 2.6 -> 2.3 2.4 2.5 * + 2.6 + */
+	e = New(nil)
+	for _, elt = range []Elt{op_23, op_add, op_24, op_mul, op_25, op_add, op_26} {
+		err = e.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	s = new_rpn_shape(e.rpn, 0)
+	if len(s.starts[4]) != 2 || s.starts[4][0] != 0 || s.starts[4][1] != 1 {
+		t.Errorf("Unexpected operands of first \"+\": %v", s.starts[4])
+	}
+	if len(s.starts[6]) != 2 || s.starts[6][0] != 0 || s.starts[6][1] != 5 {
+		t.Errorf("Unexpected operands of last \"+\": %v", s.starts[6])
+	}
+	if !s.distinct(4, 1) || !s.distinct(6, 1) || s.distinct(6, 0) || s.distinct(3, 2) {
+		t.Errorf("Unexpected lazy operands")
+	}
+
+	/* the operand following an input of the expression can be skipped */
+	s = new_rpn_shape([]*elt_cache{new_elt_cache(op_26), new_elt_cache(op_add)}, 1)
+	if s.starts[1][0] != -1 || s.starts[1][1] != 0 || !s.distinct(1, 1) {
+		t.Errorf("Unexpected operands %v", s.starts[1])
+	}
+}
+
+/* nullable int64 read from the context with the key, a variable if
+ * variable is set.
+ */
+type nullable_elt struct {
+	key string
+	variable bool
+}
+
+type nullable_key struct {
+	name string
+}
+
+func (n *nullable_elt)Precedence()(int) { return 0 }
+func (n *nullable_elt)Associativity()(int) { return 0 }
+func (n *nullable_elt)Kind()(int) { return Kind_value }
+func (n *nullable_elt)String()(string) { return "x" }
+func (n *nullable_elt)Input_types()([][]Type) { return nil }
+func (n *nullable_elt)Output_types()([][]Type) { return [][]Type{[]Type{type_int64, type_nil}} }
+func (n *nullable_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{ctx.Value(nullable_key{name: n.key}).(Value)}, nil
+}
+
+/* variable version of nullable_elt */
+type nullable_var struct {
+	nullable_elt
+}
+
+func (n *nullable_var)Variable()(interface{}) { return nullable_key{name: n.key} }
+
+/* prefix guard, true if the operand is nil when nil is set, otherwise
+ * true if the operand is not nil.
+ */
+type nil_guard struct {
+	symbol string
+	nil bool
+}
+
+func (g *nil_guard)Precedence()(int) { return 3 }
+func (g *nil_guard)Associativity()(int) { return Associativity_right }
+func (g *nil_guard)Kind()(int) { return Kind_operator }
+func (g *nil_guard)String()(string) { return g.symbol }
+func (g *nil_guard)Input_types()([][]Type) { return [][]Type{[]Type{type_int64, type_nil}} }
+func (g *nil_guard)Output_types()([][]Type) { return [][]Type{[]Type{type_bool}} }
+func (g *nil_guard)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{value_bool((in[0].Type() == type_nil) == g.nil)}, nil
+}
+func (g *nil_guard)Narrow(in [][]Type, result bool)([][]Type) {
+	if result == g.nil {
+		return [][]Type{[]Type{type_nil}}
+	}
+	return [][]Type{[]Type{type_int64}}
+}
+
+/* logical connective, the skipped operand is nil */
+type test_logic struct {
+	symbol string
+	connective int
+	precedence int
+}
+
+func (l *test_logic)Precedence()(int) { return l.precedence }
+func (l *test_logic)Associativity()(int) { return Associativity_left }
+func (l *test_logic)Kind()(int) { return Kind_operator }
+func (l *test_logic)String()(string) { return l.symbol }
+func (l *test_logic)Input_types()([][]Type) { return [][]Type{[]Type{type_bool}, []Type{type_bool}} }
+func (l *test_logic)Output_types()([][]Type) { return [][]Type{[]Type{type_bool}} }
+func (l *test_logic)Connective()(int) { return l.connective }
+func (l *test_logic)Skip(index int, previous []Value)(bool) {
+	return previous[0].(*value_t).value_bool == (l.connective == Connective_or)
+}
+func (l *test_logic)Execute(ctx context.Context, in []Value)([]Value, error) {
+	if l.Skip(1, in) {
+		return in[0:1], nil
+	}
+	return in[1:2], nil
+}
+
+func Test_narrowing(t *testing.T) {
+	var x Elt
+	var alias Elt
+	var y Elt
+	var literal Elt
+	var is_set Elt
+	var is_nil Elt
+	var and Elt
+	var or Elt
+	var gt Elt
+	var one *test_fn
+	var e *Expr
+	var v []Value
+	var ctx context.Context
+	var elt Elt
+	var err error
+	var c struct {
+		elts []Elt
+		x Value
+		expect string
+	}
+
+	x = &nullable_var{nullable_elt{key: "x"}}
+	alias = &nullable_var{nullable_elt{key: "x"}}
+	y = &nullable_var{nullable_elt{key: "y"}}
+	literal = &nullable_elt{key: "x"}
+	is_set = &nil_guard{symbol: "is_set"}
+	is_nil = &nil_guard{symbol: "is_nil", nil: true}
+	and = &test_logic{symbol: "&&", connective: Connective_and, precedence: 2}
+	or = &test_logic{symbol: "||", connective: Connective_or, precedence: 1}
+	gt = &test_fn{
+		test: test{
+			precedence: 4,
+			associativity: Associativity_left,
+			kind: Kind_operator,
+			symbol: ">",
+			input_types: [][]Type{[]Type{type_int64}, []Type{type_int64}},
+			output_types: [][]Type{[]Type{type_bool}},
+		},
+		fn: func(vs []Value)([]Value, error) {
+			return []Value{value_bool(vs[0].(*value_t).value_int64 > vs[1].(*value_t).value_int64)}, nil
+		},
+	}
+	one = &test_fn{
+		test: test{
+			kind: Kind_value,
+			symbol: "1",
+			output_types: [][]Type{[]Type{type_int64}},
+		},
+		fn: func(vs []Value)([]Value, error) {
+			return []Value{value_int64(1)}, nil
+		},
+	}
+
+	for _, c = range []struct {
+		elts []Elt
+		x Value
+		expect string
+	}{
+		{[]Elt{is_set, x, and, x, gt, one}, value_int64(2), "true"},
+		{[]Elt{is_set, x, and, x, gt, one}, value_nil(), "false"},
+		{[]Elt{is_set, x, and, alias, gt, one}, value_int64(2), "true"},
+		{[]Elt{is_nil, x, or, x, gt, one}, value_nil(), "true"},
+		{[]Elt{is_nil, x, or, x, gt, one}, value_int64(0), "false"},
+		{[]Elt{is_set, x, and, is_set, y, and, x, gt, y}, value_int64(2), "true"},
+	} {
+		e = New(nil)
+		for _, elt = range c.elts {
+			err = e.Append(elt)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+		}
+		err = e.Finalize()
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+			continue
+		}
+		ctx = context.WithValue(context.Background(), nullable_key{name: "x"}, c.x)
+		ctx = context.WithValue(ctx, nullable_key{name: "y"}, value_int64(1))
+		v, err = e.Execute(ctx, nil)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+		} else if v[0].Descr() != c.expect {
+			t.Errorf("Expect %s for %q with x = %s, got %s", c.expect, e.String(), c.x.Descr(), v[0].Descr())
+		}
+	}
+
+	/* the narrowing applies to the same variable, in the operand
+	 * evaluated after the guard
+	 */
+	for _, c.elts = range [][]Elt{
+		{x, gt, one},
+		{is_set, x, or, x, gt, one},
+		{is_nil, x, and, x, gt, one},
+		{x, gt, one, and, is_set, x},
+		{is_set, x, and, y, gt, one},
+		{is_set, x, and, literal, gt, one},
+		{is_set, literal, and, literal, gt, one},
+	} {
+		e = New(nil)
+		for _, elt = range c.elts {
+			err = e.Append(elt)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+		}
+		err = e.Finalize()
+		if err == nil {
+			t.Errorf("Expect error for %q, got no error", e.String())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package shuntingyard
+
+import "context"
+
+/* keys used to store execution options in the context */
+type ctx_key int
+
+const (
+	ctx_key_checked ctx_key = iota
+	ctx_key_tracer
+)
+
+/* execution options extracted from the context */
+type exec_opts struct {
+	checked bool
+	tracer Tracer
+}
+
+// Return a context which enable checked execution. In checked mode,
+// Execute verify each value returned by an element against the
+// declared Output_types. The option is transmitted to the nested
+// expressions.
+func With_checked(ctx context.Context)(context.Context) {
+	return context.WithValue(ctx, ctx_key_checked, true)
+}
+
+// Return a context which send execution events to the tracer t.
+// The tracer is transmitted to the nested expressions.
+func With_tracer(ctx context.Context, t Tracer)(context.Context) {
+	return context.WithValue(ctx, ctx_key_tracer, t)
+}
+
+/* extract execution options from the context */
+func get_exec_opts(ctx context.Context)(exec_opts) {
+	var opts exec_opts
+
+	opts.checked, _ = ctx.Value(ctx_key_checked).(bool)
+	opts.tracer, _ = ctx.Value(ctx_key_tracer).(Tracer)
+	return opts
+}
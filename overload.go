@@ -0,0 +1,213 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strings"
+
+// Set of elements sharing the same symbol. The candidate is chosen
+// during Finalize according with the types of the operands found in
+// the simulated type stack, and the chosen candidate replace the set
+// in the expression, so Execute calls it without lookup.
+type Overload struct {
+	symbol string
+	candidates []Elt
+}
+
+// Describe the inputs and outputs of the element, like
+// "(float64, float64) -> float64"
+func Signature(elt Elt)(string) {
+	return "(" + Type_list(elt.Input_types()) + ") -> " + Type_list(elt.Output_types())
+}
+
+// Create overload set. All the candidates must have the same kind,
+// precedence, associativity and number of inputs and outputs. When many
+// candidates match the operands, the candidate whose inputs are exactly
+// the types of the operands is chosen, otherwise the overload is ambiguous.
+func New_overload(symbol string, candidates ...Elt)(*Overload, error) {
+	var c Elt
+	var first Elt
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("Overload %q needs at least one candidate", symbol)
+	}
+	first = candidates[0]
+	if first.Kind() != Kind_operator && first.Kind() != Kind_value {
+		return nil, fmt.Errorf("Overload %q candidate kind %s can't be overloaded",
+		                       symbol, kind_str(first.Kind()))
+	}
+	for _, c = range candidates[1:] {
+		if c.Kind() != first.Kind() ||
+		   c.Precedence() != first.Precedence() ||
+		   c.Associativity() != first.Associativity() {
+			return nil, fmt.Errorf("Overload %q candidate %s doesn't share kind, precedence and associativity of %s",
+			                       symbol, Signature(c), Signature(first))
+		}
+		if len(c.Input_types()) != len(first.Input_types()) ||
+		   len(c.Output_types()) != len(first.Output_types()) {
+			return nil, fmt.Errorf("Overload %q candidate %s doesn't share number of inputs and outputs of %s",
+			                       symbol, Signature(c), Signature(first))
+		}
+	}
+	return &Overload{
+		symbol: symbol,
+		candidates: candidates,
+	}, nil
+}
+
+// Return the candidates of the set
+func (o *Overload)Candidates()([]Elt) {
+	return o.candidates
+}
+
+/* union of types of candidates at each position */
+func union_types(list [][][]Type)([][]Type) {
+	var out [][]Type
+	var types [][]Type
+	var t Type
+	var i int
+
+	out = make([][]Type, len(list[0]))
+	for _, types = range list {
+		for i = range out {
+			for _, t = range types[i] {
+				if !Has_compat([]Type{t}, out[i]) {
+					out[i] = append(out[i], t)
+				}
+			}
+		}
+	}
+	return out
+}
+
+/* Implement Elt interface for Overload */
+func (o *Overload)Precedence()(int) {
+	return o.candidates[0].Precedence()
+}
+func (o *Overload)Associativity()(int) {
+	return o.candidates[0].Associativity()
+}
+func (o *Overload)Kind()(int) {
+	return o.candidates[0].Kind()
+}
+func (o *Overload)String()(string) {
+	return o.symbol
+}
+func (o *Overload)Input_types()([][]Type) {
+	var list [][][]Type
+	var c Elt
+
+	for _, c = range o.candidates {
+		list = append(list, c.Input_types())
+	}
+	return union_types(list)
+}
+func (o *Overload)Output_types()([][]Type) {
+	var list [][][]Type
+	var c Elt
+
+	for _, c = range o.candidates {
+		list = append(list, c.Output_types())
+	}
+	return union_types(list)
+}
+func (o *Overload)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return nil, fmt.Errorf("Overload %q is not resolved, the expression must be finalized", o.symbol)
+}
+
+/* describe all the candidates */
+func (o *Overload)candidates_desc()(string) {
+	var out []string
+	var c Elt
+
+	for _, c = range o.candidates {
+		out = append(out, Signature(c))
+	}
+	return strings.Join(out, "; ")
+}
+
+/* return true if the top of the type stack is compatible with inputs */
+func stack_match(stack_types [][]Type, inputs [][]Type)(bool) {
+	var stack_index int
+	var i int
+
+	if len(stack_types) < len(inputs) {
+		return false
+	}
+	stack_index = len(stack_types) - len(inputs)
+	for i = 0; i < len(inputs); i++ {
+		if !Has_compat(stack_types[stack_index + i], inputs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+/* return the candidates whose inputs are exactly the types of the top
+ * of the stack, or all the candidates if none of them is exact. The
+ * candidate on (nil, nil) is chosen among the nullable ones for nil == nil.
+ */
+func exact_match(stack_types [][]Type, match []Elt)([]Elt) {
+	var exact []Elt
+	var c Elt
+	var inputs [][]Type
+	var stack_index int
+	var ok bool
+	var i int
+	var t Type
+
+	for _, c = range match {
+		inputs = c.Input_types()
+		stack_index = len(stack_types) - len(inputs)
+		ok = true
+		for i = range inputs {
+			for _, t = range stack_types[stack_index + i] {
+				ok = ok && types_contain(inputs[i], t)
+			}
+			for _, t = range inputs[i] {
+				ok = ok && types_contain(stack_types[stack_index + i], t)
+			}
+		}
+		if ok {
+			exact = append(exact, c)
+		}
+	}
+	if len(exact) == 0 {
+		return match
+	}
+	return exact
+}
+
+/* choose the candidate of the overload set matching the type stack */
+func resolve_overload(ec *elt_cache, stack_types [][]Type)(*elt_cache, error) {
+	var o *Overload
+	var c Elt
+	var match []Elt
+	var stack_index int
+	var got string
+
+	o = ec.elt.(*Overload)
+	for _, c = range o.candidates {
+		if stack_match(stack_types, c.Input_types()) {
+			match = append(match, c)
+		}
+	}
+
+	if len(match) > 1 {
+		match = exact_match(stack_types, match)
+	}
+	if len(match) == 1 {
+		return new_elt_cache(match[0]), nil
+	}
+
+	stack_index = len(stack_types) - len(o.candidates[0].Input_types())
+	if stack_index < 0 {
+		stack_index = 0
+	}
+	got = "(" + Type_list(stack_types[stack_index:]) + ")"
+	if len(match) == 0 {
+		return nil, fmt.Errorf("Inconsistent expression, no candidate of %q matches %s, candidates are: %s",
+		                       o.symbol, got, o.candidates_desc())
+	}
+	return nil, fmt.Errorf("Inconsistent expression, ambiguous %q with %s, candidates are: %s",
+	                       o.symbol, got, o.candidates_desc())
+}
@@ -0,0 +1,157 @@
+package shuntingyard
+
+import "context"
+import "strings"
+import "testing"
+
+var op_concat *test_fn = &test_fn{
+	test: test{
+		precedence: 1,
+		associativity: Associativity_left,
+		kind: Kind_operator,
+		symbol: "+",
+		input_types: [][]Type{[]Type{type_string},[]Type{type_string}},
+		output_types: [][]Type{[]Type{type_string}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		return []Value{value_string(vs[0].(*value_t).value_string + vs[1].(*value_t).value_string)}, nil
+	},
+}
+
+var op_str_a *test_fn = &test_fn{
+	test: test{
+		kind: Kind_value,
+		symbol: "\"a\"",
+		output_types: [][]Type{[]Type{type_string}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		return []Value{value_string("a")}, nil
+	},
+}
+
+func Test_overload(t *testing.T) {
+	var o *Overload
+	var e *Expr
+	var err error
+	var v []Value
+
+	_, err = New_overload("+")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = New_overload("+", op_add, op_mul)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = New_overload("+", op_add, op_neg)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = New_overload("(", op_open)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	o, err = New_overload("+", op_add, op_concat)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if Type_list(o.Input_types()) != "float64|string, float64|string" {
+		t.Errorf("Expect union of input types, got %q", Type_list(o.Input_types()))
+	}
+
+	/* numeric addition */
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(o)
+	e.Append(op_24)
+	e.Append(o)
+	e.Append(op_25)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if e.rpn[2].elt != op_add || e.rpn[4].elt != op_add {
+		t.Errorf("Expect overload resolved as numeric addition")
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "7.200000" {
+		t.Errorf("Expect 7.2, got %s", v[0].Descr())
+	}
+
+	/* string concatenation */
+	e = New(nil)
+	e.Append(op_str_a)
+	e.Append(o)
+	e.Append(op_str_a)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "\"aa\"" {
+		t.Errorf("Expect \"aa\", got %s", v[0].Descr())
+	}
+
+	/* no match */
+	e = New(nil)
+	e.Append(op_str_a)
+	e.Append(o)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if !strings.Contains(err.Error(), "no candidate") ||
+	          !strings.Contains(err.Error(), "(string, float64)") ||
+	          !strings.Contains(err.Error(), "(float64, float64) -> float64; (string, string) -> string") {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	/* ambiguity */
+	o, _ = New_overload("+", op_add, op_add_error)
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(o)
+	e.Append(op_24)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	_, err = o.Execute(context.Background(), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* the exact candidate is chosen among the matching ones */
+	o, _ = New_overload("+", &test_fn{
+		test: test{
+			precedence: 1,
+			associativity: Associativity_left,
+			kind: Kind_operator,
+			symbol: "+",
+			input_types: [][]Type{[]Type{type_float64, type_string},[]Type{type_float64, type_string}},
+			output_types: [][]Type{[]Type{type_string}},
+		},
+		fn: func(vs []Value)([]Value, error) {
+			return []Value{value_string("mixed")}, nil
+		},
+	}, op_add)
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(o)
+	e.Append(op_24)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if e.rpn[2].elt != op_add {
+		t.Errorf("Expect overload resolved as numeric addition")
+	}
+}
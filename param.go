@@ -0,0 +1,324 @@
+package shuntingyard
+
+import "fmt"
+import "strings"
+
+// Parametric type, like list<float64> or map<string, int64>. Two
+// parametric types are equal if they have the same base name and equal
+// arguments. They are covariant: list<int> is assignable to list<number>
+// if int is assignable to number, except the parameters of the function
+// types which are contravariant.
+type Param_type struct {
+	base string
+	args []Type
+	// optional names of the arguments, used by the tuples
+	names []string
+}
+
+// Create parametric type
+func New_param_type(base string, args ...Type)(*Param_type) {
+	return &Param_type{
+		base: base,
+		args: args,
+	}
+}
+
+// Create type list<t>
+func List_type(t Type)(*Param_type) {
+	return New_param_type("list", t)
+}
+
+// Create type map<k, v>
+func Map_type(k Type, v Type)(*Param_type) {
+	return New_param_type("map", k, v)
+}
+
+// Create type optional<t>
+func Optional_type(t Type)(*Param_type) {
+	return New_param_type("optional", t)
+}
+
+func (p *Param_type)Name()(string) {
+	var names []string
+	var t Type
+	var i int
+
+	for i, t = range p.args {
+		if len(p.names) == len(p.args) {
+			names = append(names, p.names[i] + ": " + t.Name())
+		} else {
+			names = append(names, t.Name())
+		}
+	}
+	return p.base + "<" + strings.Join(names, ", ") + ">"
+}
+
+// Return the base name, like "list"
+func (p *Param_type)Base()(string) {
+	return p.base
+}
+
+// Return the arguments of the type
+func (p *Param_type)Args()([]Type) {
+	return p.args
+}
+
+func (p *Param_type)Assignable_to(t Type)(bool) {
+	var r *Param_type
+	var ok bool
+	var i int
+
+	r, ok = t.(*Param_type)
+	if !ok || r.base != p.base || len(r.args) != len(p.args) {
+		return false
+	}
+	for i = range p.args {
+
+		/* the parameters of the functions are contravariant */
+		if p.base == "func" && i < len(p.args) - 1 {
+			if !Is_assignable(r.args[i], p.args[i]) {
+				return false
+			}
+			continue
+		}
+		if !Is_assignable(p.args[i], r.args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Structural type equality. The types are equal if they are the same,
+// or if they are parametric types with same base and equal arguments.
+func Type_equal(a Type, b Type)(bool) {
+	var pa *Param_type
+	var pb *Param_type
+	var ok bool
+	var i int
+
+	if a == b {
+		return true
+	}
+	pa, ok = a.(*Param_type)
+	if !ok {
+		return false
+	}
+	pb, ok = b.(*Param_type)
+	if !ok || pa.base != pb.base || len(pa.args) != len(pb.args) {
+		return false
+	}
+	for i = range pa.args {
+		if !Type_equal(pa.args[i], pb.args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+/* return true if the list contains the type t */
+func types_contain(types []Type, t Type)(bool) {
+	var c Type
+
+	for _, c = range types {
+		if Type_equal(c, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Type variable used in Input_types and Output_types of generic elements,
+// like append(list<T>, T) -> list<T>. During Finalize, the variable is bound
+// by the first operand which contains it, the other operands must match the
+// binding, and the variable is replaced by its binding in the output types.
+type Type_var struct {
+	name string
+}
+
+func New_type_var(name string)(*Type_var) {
+	return &Type_var{
+		name: name,
+	}
+}
+
+func (v *Type_var)Name()(string) {
+	return v.name
+}
+
+/* return true if the type contains type variable */
+func type_has_var(t Type)(bool) {
+	var p *Param_type
+	var a Type
+	var ok bool
+
+	_, ok = t.(*Type_var)
+	if ok {
+		return true
+	}
+	p, ok = t.(*Param_type)
+	if !ok {
+		return false
+	}
+	for _, a = range p.args {
+		if type_has_var(a) {
+			return true
+		}
+	}
+	return false
+}
+
+/* return true if the list of alternatives contains type variable */
+func types_have_var(types [][]Type)(bool) {
+	var alt []Type
+	var t Type
+
+	for _, alt = range types {
+		for _, t = range alt {
+			if type_has_var(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* bindings of type variables for one element */
+type type_bindings struct {
+	bound map[*Type_var][]Type
+	frozen map[*Type_var]bool
+}
+
+/* unify provided type p with required type r */
+func (b *type_bindings)unify(p Type, r Type)(bool) {
+	var v *Type_var
+	var pp *Param_type
+	var pr *Param_type
+	var ok bool
+	var i int
+
+	v, ok = r.(*Type_var)
+	if ok {
+		if b.frozen[v] {
+			return Has_compat([]Type{p}, b.bound[v])
+		}
+		if !types_contain(b.bound[v], p) {
+			b.bound[v] = append(b.bound[v], p)
+		}
+		return true
+	}
+
+	pr, ok = r.(*Param_type)
+	if ok && type_has_var(pr) {
+		pp, ok = p.(*Param_type)
+		if !ok || pp.base != pr.base || len(pp.args) != len(pr.args) {
+			return false
+		}
+		for i = range pr.args {
+			if !b.unify(pp.args[i], pr.args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return Is_assignable(p, r)
+}
+
+/* replace type variables by their bindings */
+func (b *type_bindings)subst(t Type)([]Type, error) {
+	var v *Type_var
+	var p *Param_type
+	var args []Type
+	var alt []Type
+	var err error
+	var ok bool
+	var i int
+
+	v, ok = t.(*Type_var)
+	if ok {
+		if len(b.bound[v]) == 0 {
+			return nil, fmt.Errorf("type variable %s is not bound", v.name)
+		}
+		return b.bound[v], nil
+	}
+
+	p, ok = t.(*Param_type)
+	if !ok || !type_has_var(p) {
+		return []Type{t}, nil
+	}
+	args = make([]Type, len(p.args))
+	for i = range p.args {
+		alt, err = b.subst(p.args[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(alt) != 1 {
+			return nil, fmt.Errorf("type argument of %s can't be the alternative %s",
+			                       p.Name(), Type_desc(alt))
+		}
+		args[i] = alt[0]
+	}
+	return []Type{&Param_type{base: p.base, args: args, names: p.names}}, nil
+}
+
+/* unify the operands types with the generic inputs, and return
+ * the output types with bound type variables.
+ */
+func unify_types(operands [][]Type, inputs [][]Type, outputs [][]Type)([][]Type, error) {
+	var b *type_bindings
+	var i int
+	var p Type
+	var r Type
+	var v *Type_var
+	var matched bool
+	var out [][]Type
+	var decl []Type
+	var alt []Type
+	var subst []Type
+	var t Type
+	var err error
+
+	b = &type_bindings{
+		bound: make(map[*Type_var][]Type),
+		frozen: make(map[*Type_var]bool),
+	}
+
+	for i = range inputs {
+
+		/* each provided alternative must match one required alternative */
+		for _, p = range operands[i] {
+			matched = false
+			for _, r = range inputs[i] {
+				if b.unify(p, r) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("needs %s, got %s", Type_desc(inputs[i]), Type_desc(operands[i]))
+			}
+		}
+
+		/* the variables bound by this operand can't be extended */
+		for v = range b.bound {
+			b.frozen[v] = true
+		}
+	}
+
+	for _, decl = range outputs {
+		alt = nil
+		for _, t = range decl {
+			subst, err = b.subst(t)
+			if err != nil {
+				return nil, err
+			}
+			for _, t = range subst {
+				if !types_contain(alt, t) {
+					alt = append(alt, t)
+				}
+			}
+		}
+		out = append(out, alt)
+	}
+	return out, nil
+}
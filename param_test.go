@@ -0,0 +1,198 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strings"
+import "testing"
+
+type list_value struct {
+	elem Type
+	items []Value
+}
+
+func (l *list_value)Descr()(string) {
+	var out []string
+	var v Value
+
+	for _, v = range l.items {
+		out = append(out, v.Descr())
+	}
+	return "[" + strings.Join(out, " ") + "]"
+}
+
+func (l *list_value)Type()(Type) {
+	return List_type(l.elem)
+}
+
+var type_var_t *Type_var = New_type_var("T")
+
+var op_empty_list *test_fn = &test_fn{
+	test: test{
+		kind: Kind_value,
+		symbol: "[]",
+		output_types: [][]Type{[]Type{List_type(type_float64)}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		return []Value{&list_value{elem: type_float64}}, nil
+	},
+}
+
+var op_append *test_fn = &test_fn{
+	test: test{
+		precedence: 1,
+		associativity: Associativity_left,
+		kind: Kind_operator,
+		symbol: "append",
+		input_types: [][]Type{[]Type{List_type(type_var_t)}, []Type{type_var_t}},
+		output_types: [][]Type{[]Type{List_type(type_var_t)}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		var l *list_value
+
+		l = vs[0].(*list_value)
+		return []Value{&list_value{elem: l.elem, items: append(append([]Value(nil), l.items...), vs[1])}}, nil
+	},
+}
+
+var op_first *test_fn = &test_fn{
+	test: test{
+		precedence: 3,
+		associativity: Associativity_right,
+		kind: Kind_operator,
+		symbol: "first",
+		input_types: [][]Type{[]Type{List_type(type_var_t)}},
+		output_types: [][]Type{[]Type{type_var_t}},
+	},
+	fn: func(vs []Value)([]Value, error) {
+		var l *list_value
+
+		l = vs[0].(*list_value)
+		if len(l.items) == 0 {
+			return nil, fmt.Errorf("empty list")
+		}
+		return []Value{l.items[0]}, nil
+	},
+}
+
+func Test_param_types(t *testing.T) {
+	var e *Expr
+	var err error
+	var v []Value
+
+	if Type_string(Map_type(type_string, List_type(type_float64))) != "map<string, list<float64>>" {
+		t.Errorf("Unexpected type name %q", Type_string(Map_type(type_string, List_type(type_float64))))
+	}
+	if Type_list([][]Type{[]Type{Optional_type(type_bool), type_nil}, []Type{type_var_t}}) != "optional<bool>|nil, T" {
+		t.Errorf("Unexpected type list")
+	}
+	if !Type_equal(List_type(type_float64), List_type(type_float64)) {
+		t.Errorf("Expect equal types")
+	}
+	if Type_equal(List_type(type_float64), List_type(type_bool)) ||
+	   Type_equal(List_type(type_float64), Optional_type(type_float64)) ||
+	   Type_equal(List_type(type_float64), type_float64) {
+		t.Errorf("Expect different types")
+	}
+	if !Has_compat([]Type{List_type(type_int)}, []Type{List_type(type_number)}) {
+		t.Errorf("Expect list<int> assignable to list<number>")
+	}
+	if Has_compat([]Type{Map_type(type_string, type_int)}, []Type{Map_type(type_string, type_real)}) {
+		t.Errorf("Expect map<string, int> not assignable to map<string, real>")
+	}
+
+	/* append(list<T>, T) -> list<T> */
+	e = New(nil)
+	e.Append(op_first)
+	e.Append(op_empty_list)
+	e.Append(op_append)
+	e.Append(op_23)
+	e.Append(op_append)
+	e.Append(op_24)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	e = New(nil)
+	e.Append(op_open)
+	e.Append(op_empty_list)
+	e.Append(op_append)
+	e.Append(op_23)
+	e.Append(op_append)
+	e.Append(op_24)
+	e.Append(op_close)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if Type_list(e.Output_types()) != "list<float64>" {
+		t.Errorf("Expect \"list<float64>\", got %q", Type_list(e.Output_types()))
+	}
+	v, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "[2.300000 2.400000]" {
+		t.Errorf("Unexpected result %s", v[0].Descr())
+	}
+
+	/* first(list<T>) -> T feeds "+" */
+	e = New(nil)
+	e.Append(op_first)
+	e.Append(op_open)
+	e.Append(op_empty_list)
+	e.Append(op_append)
+	e.Append(op_23)
+	e.Append(op_close)
+	e.Append(op_add)
+	e.Append(op_24)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	} else if v[0].Descr() != "4.700000" {
+		t.Errorf("Unexpected result %s", v[0].Descr())
+	}
+
+	/* operands don't unify */
+	e = New(nil)
+	e.Append(op_empty_list)
+	e.Append(op_append)
+	e.Append(op_true)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if err.Error() != "Inconsistent expression, \"append\" needs T, got bool" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	/* not a list */
+	e = New(nil)
+	e.Append(op_first)
+	e.Append(op_23)
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* type variable bound to alternative can't be type argument */
+	e = New(nil)
+	e.Append(op_26_or_nil)
+	e.Append(&test_fn{
+		test: test{
+			precedence: 3,
+			kind: Kind_operator,
+			symbol: "singleton",
+			input_types: [][]Type{[]Type{type_var_t}},
+			output_types: [][]Type{[]Type{List_type(type_var_t)}},
+		},
+	})
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	} else if !strings.Contains(err.Error(), "float64|nil") {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
@@ -0,0 +1,194 @@
+package shuntingyard
+
+import "fmt"
+import "io"
+import "net/http"
+import "sort"
+import "strings"
+import "sync"
+import "time"
+
+// Aggregated metrics of one element symbol or one expression
+type Profile_entry struct {
+	Calls uint64
+	Errors uint64
+	Total time.Duration
+	Max time.Duration
+}
+
+// Copy of the metrics aggregated by the Profiler. Elements are indexed
+// by symbol, expressions by name.
+type Profile_snapshot struct {
+	Elements map[string]Profile_entry
+	Exprs map[string]Profile_entry
+}
+
+// Tracer which aggregates execution metrics per element symbol and per
+// expression. It is set using With_tracer and it is safe for concurrent
+// use, so one profiler can observe many evaluations. The profiler
+// implements http.Handler and serves the metrics using the Prometheus
+// text exposition format.
+type Profiler struct {
+	lock sync.Mutex
+	elements map[string]*Profile_entry
+	exprs map[string]*Profile_entry
+}
+
+func New_profiler()(*Profiler) {
+	return &Profiler{
+		elements: make(map[string]*Profile_entry),
+		exprs: make(map[string]*Profile_entry),
+	}
+}
+
+func (pe *Profile_entry)add(err error, d time.Duration) {
+	pe.Calls++
+	if err != nil {
+		pe.Errors++
+	}
+	pe.Total += d
+	if d > pe.Max {
+		pe.Max = d
+	}
+}
+
+/* get or create entry */
+func profile_get(m map[string]*Profile_entry, key string)(*Profile_entry) {
+	var pe *Profile_entry
+
+	pe = m[key]
+	if pe == nil {
+		pe = &Profile_entry{}
+		m[key] = pe
+	}
+	return pe
+}
+
+func (p *Profiler)Enter(e *Expr, in []Value) {
+}
+
+func (p *Profiler)Leave(e *Expr, out []Value, err error, d time.Duration) {
+	p.lock.Lock()
+	profile_get(p.exprs, e.String()).add(err, d)
+	p.lock.Unlock()
+}
+
+func (p *Profiler)Before(e *Expr, pos int, elt Elt, in []Value) {
+}
+
+func (p *Profiler)After(e *Expr, pos int, elt Elt, in []Value, out []Value, err error, d time.Duration) {
+	var ok bool
+
+	/* nested expressions are accounted by Leave */
+	_, ok = elt.(*Expr)
+	if ok {
+		return
+	}
+	/* the operands skipped by their connective are not executed */
+	_, ok = elt.(*skipped_elt)
+	if ok {
+		return
+	}
+	p.lock.Lock()
+	profile_get(p.elements, elt.String()).add(err, d)
+	p.lock.Unlock()
+}
+
+// Forget all the aggregated metrics
+func (p *Profiler)Reset() {
+	p.lock.Lock()
+	p.elements = make(map[string]*Profile_entry)
+	p.exprs = make(map[string]*Profile_entry)
+	p.lock.Unlock()
+}
+
+// Return a copy of the aggregated metrics
+func (p *Profiler)Snapshot()(*Profile_snapshot) {
+	var s *Profile_snapshot
+	var key string
+	var pe *Profile_entry
+
+	s = &Profile_snapshot{
+		Elements: make(map[string]Profile_entry),
+		Exprs: make(map[string]Profile_entry),
+	}
+	p.lock.Lock()
+	for key, pe = range p.elements {
+		s.Elements[key] = *pe
+	}
+	for key, pe = range p.exprs {
+		s.Exprs[key] = *pe
+	}
+	p.lock.Unlock()
+	return s
+}
+
+/* description of one prometheus metric */
+type prometheus_metric struct {
+	suffix string
+	kind string
+	help string
+	value func(pe Profile_entry)(string)
+}
+
+var prometheus_metrics []prometheus_metric = []prometheus_metric{
+	{"calls_total", "counter", "Number of executions.",
+	 func(pe Profile_entry)(string) { return fmt.Sprintf("%d", pe.Calls) }},
+	{"errors_total", "counter", "Number of executions which return an error.",
+	 func(pe Profile_entry)(string) { return fmt.Sprintf("%d", pe.Errors) }},
+	{"duration_seconds_total", "counter", "Cumulative execution duration.",
+	 func(pe Profile_entry)(string) { return fmt.Sprintf("%g", pe.Total.Seconds()) }},
+	{"duration_seconds_max", "gauge", "Maximum execution duration.",
+	 func(pe Profile_entry)(string) { return fmt.Sprintf("%g", pe.Max.Seconds()) }},
+}
+
+/* escape prometheus label value */
+var prometheus_escape = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+/* write one family of metrics using the prometheus format */
+func write_prometheus_family(w io.Writer, m map[string]Profile_entry, prefix string, label string)(error) {
+	var keys []string
+	var key string
+	var metric prometheus_metric
+	var err error
+
+	for key = range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, metric = range prometheus_metrics {
+		_, err = fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n",
+		                     prefix, metric.suffix, metric.help, prefix, metric.suffix, metric.kind)
+		if err != nil {
+			return err
+		}
+		for _, key = range keys {
+			_, err = fmt.Fprintf(w, "%s_%s{%s=\"%s\"} %s\n", prefix, metric.suffix, label,
+			                     prometheus_escape.Replace(key), metric.value(m[key]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Write the aggregated metrics using the Prometheus text exposition format
+func (p *Profiler)Write_prometheus(w io.Writer)(error) {
+	var s *Profile_snapshot
+	var err error
+
+	s = p.Snapshot()
+	err = write_prometheus_family(w, s.Elements, "shuntingyard_element", "symbol")
+	if err != nil {
+		return err
+	}
+	return write_prometheus_family(w, s.Exprs, "shuntingyard_expr", "expr")
+}
+
+// Serve the aggregated metrics using the Prometheus text exposition format
+func (p *Profiler)ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.Write_prometheus(w)
+}
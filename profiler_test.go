@@ -0,0 +1,124 @@
+package shuntingyard
+
+import "context"
+import "io"
+import "net/http"
+import "net/http/httptest"
+import "strings"
+import "testing"
+
+func Test_profiler(t *testing.T) {
+	var e *Expr
+	var se *Expr
+	var p *Profiler
+	var s *Profile_snapshot
+	var ctx context.Context
+	var srv *httptest.Server
+	var resp *http.Response
+	var body []byte
+	var err error
+	var i int
+	var expect string
+	var ok bool
+
+	se = New(nil)
+	se.Append(op_23)
+	se.Append(op_add)
+	se.Append(op_24)
+	se.Set_name("sub")
+	se.Finalize()
+
+	e = New(nil)
+	e.Append(se)
+	e.Append(op_add)
+	e.Append(op_25)
+	e.Set_name("main")
+	e.Finalize()
+
+	p = New_profiler()
+	ctx = With_tracer(context.Background(), p)
+	for i = 0; i < 3; i++ {
+		_, err = e.Execute(ctx, nil)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+		}
+	}
+
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(op_add_error)
+	e.Append(op_24)
+	e.Finalize()
+	e.Execute(ctx, nil)
+
+	s = p.Snapshot()
+	if s.Elements["+"].Calls != 6 {
+		t.Errorf("Expect 6 calls of \"+\", got %d", s.Elements["+"].Calls)
+	}
+	if s.Elements["+e"].Calls != 1 || s.Elements["+e"].Errors != 1 {
+		t.Errorf("Expect 1 failed call of \"+e\", got %#v", s.Elements["+e"])
+	}
+	if s.Exprs["main"].Calls != 3 || s.Exprs["sub"].Calls != 3 {
+		t.Errorf("Expect 3 calls of \"main\" and \"sub\"")
+	}
+	if s.Exprs["2.3 +e 2.4"].Errors != 1 {
+		t.Errorf("Expect 1 error for \"2.3 +e 2.4\"")
+	}
+	if s.Exprs["main"].Max > s.Exprs["main"].Total {
+		t.Errorf("Expect max duration lower than total duration")
+	}
+	_, ok = s.Elements["sub"]
+	if ok {
+		t.Errorf("Expect nested expression not accounted as element")
+	}
+
+	srv = httptest.NewServer(p)
+	defer srv.Close()
+	resp, err = srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, expect = range []string{
+		"# TYPE shuntingyard_element_calls_total counter\n",
+		"shuntingyard_element_calls_total{symbol=\"+\"} 6\n",
+		"shuntingyard_element_errors_total{symbol=\"+e\"} 1\n",
+		"shuntingyard_expr_calls_total{expr=\"main\"} 3\n",
+		"# TYPE shuntingyard_expr_duration_seconds_max gauge\n",
+	} {
+		if !strings.Contains(string(body), expect) {
+			t.Errorf("Expect %q in metrics, got:\n%s", expect, string(body))
+		}
+	}
+
+	p.Reset()
+	if len(p.Snapshot().Elements) != 0 {
+		t.Errorf("Expect empty profile after reset")
+	}
+	/* false && true, the right operand is skipped */
+	e = New(nil)
+	e.Append(op_false)
+	e.Append(&test_logic{symbol: "&&", connective: Connective_and, precedence: 2})
+	e.Append(op_true)
+	e.Finalize()
+	_, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	s = p.Snapshot()
+	if s.Elements["&&"].Calls != 1 || s.Elements["false"].Calls != 1 {
+		t.Errorf("Expect 1 call of \"&&\" and \"false\", got %#v", s.Elements)
+	}
+	_, ok = s.Elements["true"]
+	if ok {
+		t.Errorf("Expect skipped operand not accounted")
+	}
+	_, ok = s.Elements["<skipped>"]
+	if ok {
+		t.Errorf("Expect skipped operand not accounted as element")
+	}
+}
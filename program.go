@@ -0,0 +1,318 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+
+// Precedence of the assignment operators, lower than all the other operators
+const Precedence_assign = Precedence_let - 1
+
+/* statement separator */
+type separator struct {}
+
+func (s *separator)Precedence()(int) {
+	return 0
+}
+func (s *separator)Associativity()(int) {
+	return 0
+}
+func (s *separator)Kind()(int) {
+	return Kind_operator
+}
+func (s *separator)String()(string) {
+	return ";"
+}
+func (s *separator)Input_types()([][]Type) {
+	return nil
+}
+func (s *separator)Output_types()([][]Type) {
+	return nil
+}
+func (s *separator)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return nil, fmt.Errorf("Statement separator can't be executed")
+}
+
+// Statement separator of the programs
+var Separator Elt = &separator{}
+
+/* key of the environment of the program in the context */
+type program_key struct {
+	p *Program
+}
+
+/* values of the variables of the program */
+type program_env struct {
+	values map[string]Value
+}
+
+// Program is a sequence of statements executed in order and sharing an
+// environment. The statements are built with Append and separated by
+// Separator, each statement is finalized at the separator, so the
+// variables assigned by a statement are available with their type in
+// the following statements. The assignments are built with Assign and
+// the variables are read with Var. The type of a variable is the type of
+// its first assignment, the following assignments must be compatible.
+type Program struct {
+	statements []*Expr
+	current *Expr
+	types map[string][]Type
+	done bool
+}
+
+func New_program()(*Program) {
+	return &Program{
+		current: New(nil),
+		types: make(map[string][]Type),
+	}
+}
+
+// Append element to the current statement. Separator ends the statement.
+func (p *Program)Append(elt Elt)(error) {
+	if p.done {
+		return fmt.Errorf("Program already finalized")
+	}
+	if elt == Separator {
+		return p.end_statement()
+	}
+	return p.current.Append(elt)
+}
+
+/* finalize the current statement and declare its assignment */
+func (p *Program)end_statement()(error) {
+	var st *Expr
+	var a *assign
+	var ec *elt_cache
+	var t []Type
+	var pos int
+	var ok bool
+	var err error
+
+	st = p.current
+	if len(st.rpn) == 0 && len(st.precedence_stack) == 0 {
+		return fmt.Errorf("Program error, empty statement #%d", len(p.statements))
+	}
+	err = st.Finalize()
+	if err != nil {
+		return fmt.Errorf("Statement #%d: %s", len(p.statements), err.Error())
+	}
+
+	/* the assignment must be the last operation of the statement */
+	for pos, ec = range st.rpn {
+		a, ok = ec.elt.(*assign)
+		if !ok {
+			continue
+		}
+		if a.p != p {
+			return fmt.Errorf("Statement #%d: assignment of %q from another program", len(p.statements), a.name)
+		}
+		if pos != len(st.rpn) - 1 || len(st.output_types) != 1 {
+			return fmt.Errorf("Statement #%d: assignment of %q must be the statement", len(p.statements), a.name)
+		}
+		/* the variables taken before keep the type of the first assignment */
+		t, ok = p.types[a.name]
+		if ok && !Has_compat(st.output_types[0], t) {
+			return fmt.Errorf("Statement #%d: can't assign %s to %q of type %s",
+			                  len(p.statements), Type_desc(st.output_types[0]), a.name, Type_desc(t))
+		}
+		if !ok {
+			p.types[a.name] = st.output_types[0]
+		}
+	}
+
+	p.statements = append(p.statements, st)
+	p.current = New(nil)
+	return nil
+}
+
+// Finalize the last statement. The separator after the last statement is
+// optional.
+func (p *Program)Finalize()(error) {
+	var err error
+
+	if p.done {
+		return fmt.Errorf("Program already finalized")
+	}
+	if len(p.current.rpn) != 0 || len(p.current.precedence_stack) != 0 {
+		err = p.end_statement()
+		if err != nil {
+			return err
+		}
+	}
+	if len(p.statements) == 0 {
+		return fmt.Errorf("Program error, no statement")
+	}
+	p.done = true
+	return nil
+}
+
+// Return the finalized statements
+func (p *Program)Statements()([]*Expr) {
+	return p.statements
+}
+
+// Return the types of the values returned by the last statement
+func (p *Program)Output_types()([][]Type) {
+	if len(p.statements) == 0 {
+		return nil
+	}
+	return p.statements[len(p.statements) - 1].Output_types()
+}
+
+// Return the types of the variable name assigned by the finalized
+// statements
+func (p *Program)Var_types(name string)([]Type, bool) {
+	var t []Type
+	var ok bool
+
+	t, ok = p.types[name]
+	return t, ok
+}
+
+// Return prefix operator which assigns its operand to the variable name,
+// and returns it. The assignment must be the last operation of its
+// statement: "x = a + b". Its precedence is Precedence_assign.
+func (p *Program)Assign(name string)(Elt) {
+	return &assign{p: p, name: name}
+}
+
+// Return value element which reads the variable name. The variable must
+// be assigned by a previous statement, the element has the type of the
+// variable.
+func (p *Program)Var(name string)(Elt, error) {
+	var t []Type
+	var ok bool
+
+	t, ok = p.types[name]
+	if !ok {
+		return nil, fmt.Errorf("Variable %q is not assigned", name)
+	}
+	return &program_var{
+		p: p,
+		name: name,
+		output_types: [][]Type{t},
+	}, nil
+}
+
+// Execute the statements in order, and return the values of the last one
+func (p *Program)Execute(ctx context.Context)([]Value, error) {
+	var env *program_env
+	var st *Expr
+	var out []Value
+	var i int
+	var err error
+
+	if !p.done {
+		return nil, fmt.Errorf("Program is not finalized")
+	}
+	env = &program_env{
+		values: make(map[string]Value),
+	}
+	ctx = context.WithValue(ctx, program_key{p: p}, env)
+	for i, st = range p.statements {
+		out, err = st.Execute(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Statement #%d: %s", i, err.Error())
+		}
+	}
+	return out, nil
+}
+
+/* return the environment of the program p */
+func get_env(ctx context.Context, p *Program)(*program_env, error) {
+	var env *program_env
+	var ok bool
+
+	env, ok = ctx.Value(program_key{p: p}).(*program_env)
+	if !ok {
+		return nil, fmt.Errorf("Variables need the execution of their program")
+	}
+	return env, nil
+}
+
+var assign_value *Type_var = New_type_var("value")
+
+/* assignment operator */
+type assign struct {
+	p *Program
+	name string
+}
+
+func (a *assign)Precedence()(int) {
+	return Precedence_assign
+}
+func (a *assign)Associativity()(int) {
+	return Associativity_right
+}
+func (a *assign)Kind()(int) {
+	return Kind_operator
+}
+func (a *assign)String()(string) {
+	return a.name + " ="
+}
+func (a *assign)Input_types()([][]Type) {
+	return [][]Type{[]Type{assign_value}}
+}
+func (a *assign)Output_types()([][]Type) {
+	return [][]Type{[]Type{assign_value}}
+}
+func (a *assign)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var env *program_env
+	var err error
+
+	env, err = get_env(ctx, a.p)
+	if err != nil {
+		return nil, err
+	}
+	env.values[a.name] = in[0]
+	return in, nil
+}
+
+/* key of the variable name of the program */
+type program_var_key struct {
+	p *Program
+	name string
+}
+
+/* variable of the program */
+type program_var struct {
+	p *Program
+	name string
+	output_types [][]Type
+}
+
+func (v *program_var)Precedence()(int) {
+	return 0
+}
+func (v *program_var)Associativity()(int) {
+	return 0
+}
+func (v *program_var)Kind()(int) {
+	return Kind_value
+}
+func (v *program_var)String()(string) {
+	return v.name
+}
+func (v *program_var)Input_types()([][]Type) {
+	return nil
+}
+func (v *program_var)Output_types()([][]Type) {
+	return v.output_types
+}
+func (v *program_var)Variable()(interface{}) {
+	return program_var_key{p: v.p, name: v.name}
+}
+func (v *program_var)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var env *program_env
+	var val Value
+	var ok bool
+	var err error
+
+	env, err = get_env(ctx, v.p)
+	if err != nil {
+		return nil, err
+	}
+	val, ok = env.values[v.name]
+	if !ok {
+		return nil, fmt.Errorf("Variable %q is not assigned", v.name)
+	}
+	return []Value{val}, nil
+}
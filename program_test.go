@@ -0,0 +1,124 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+func Test_program(t *testing.T) {
+	var p *Program
+	var add Elt
+	var mul Elt
+	var x Elt
+	var y Elt
+	var v []Value
+	var elt Elt
+	var types []Type
+	var ok bool
+	var err error
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+
+	/* x = 2 + 3 ; y = x * x ; x = y + x ; y + x */
+	p = New_program()
+	for _, elt = range []Elt{p.Assign("x"), Constant("2", int64(2)), add, Constant("3", int64(3)), Separator} {
+		err = p.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	_, err = p.Var("y")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	x, err = p.Var("x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, elt = range []Elt{p.Assign("y"), x, mul, x, Separator} {
+		err = p.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	y, _ = p.Var("y")
+	for _, elt = range []Elt{p.Assign("x"), y, add, x, Separator, y, add, x} {
+		err = p.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	err = p.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(p.Statements()) != 4 || p.Statements()[1].String() != "y = x * x" {
+		t.Errorf("Unexpected statements")
+	}
+	if Type_list(p.Output_types()) != "int64" {
+		t.Errorf("Expect int64, got %s", Type_list(p.Output_types()))
+	}
+	types, ok = p.Var_types("y")
+	if !ok || Type_desc(types) != "int64" {
+		t.Errorf("Expect int64 for y")
+	}
+	v, err = p.Execute(With_checked(context.Background()))
+	if err != nil || v[0].Descr() != "55" {
+		t.Errorf("Expect 55, got %v %v", v, err)
+	}
+
+	/* the variables need their program */
+	_, err = x.Execute(context.Background(), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* errors */
+	p = New_program()
+	err = p.Append(Separator)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = p.Finalize()
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* 1 + x = 2 */
+	p = New_program()
+	p.Append(Constant("1", int64(1)))
+	p.Append(add)
+	err = p.Append(p.Assign("x"))
+	if err == nil || err.Error() != `Syntax error, missing operand of "+" before "x ="` {
+		t.Errorf("Expect missing operand error, got %v", err)
+	}
+
+	/* type error in the second statement */
+	p = New_program()
+	p.Append(p.Assign("x"))
+	p.Append(Constant("s", "s"))
+	p.Append(Separator)
+	x, _ = p.Var("x")
+	p.Append(x)
+	p.Append(add)
+	p.Append(Constant("1", int64(1)))
+	err = p.Finalize()
+	if err == nil || err.Error()[:13] != "Statement #1:" {
+		t.Errorf("Expect error in statement #1, got %v", err)
+	}
+	/* x = 1 ; x = "s" ; x + 1, the reassignment keeps the type of x */
+	p = New_program()
+	p.Append(p.Assign("x"))
+	p.Append(Constant("1", int64(1)))
+	p.Append(Separator)
+	x, _ = p.Var("x")
+	p.Append(p.Assign("x"))
+	p.Append(Constant("s", "s"))
+	err = p.Append(Separator)
+	if err == nil || err.Error() != "Statement #1: can't assign string to \"x\" of type int64" {
+		t.Errorf("Expect assignment error in statement #1, got %v", err)
+	}
+	types, _ = p.Var_types("x")
+	if Type_desc(types) != "int64" {
+		t.Errorf("Expect int64 for x, got %s", Type_desc(types))
+	}
+}
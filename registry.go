@@ -0,0 +1,53 @@
+package shuntingyard
+
+import "fmt"
+
+// Set of elements indexed by symbol. It is used to find elements by
+// their symbol, and to list the elements available in a grammar.
+type Registry struct {
+	elts map[string]Elt
+	order []string
+}
+
+func New_registry()(*Registry) {
+	return &Registry{
+		elts: make(map[string]Elt),
+	}
+}
+
+// Register elements. The symbol of each element must be unique, use
+// Overload to register many elements with the same symbol.
+func (r *Registry)Add(elts ...Elt)(error) {
+	var elt Elt
+	var ok bool
+
+	for _, elt = range elts {
+		_, ok = r.elts[elt.String()]
+		if ok {
+			return fmt.Errorf("Symbol %q already registered", elt.String())
+		}
+		r.elts[elt.String()] = elt
+		r.order = append(r.order, elt.String())
+	}
+	return nil
+}
+
+// Return the element registered with symbol
+func (r *Registry)Get(symbol string)(Elt, bool) {
+	var elt Elt
+	var ok bool
+
+	elt, ok = r.elts[symbol]
+	return elt, ok
+}
+
+// Return all the registered elements in registration order
+func (r *Registry)Elts()([]Elt) {
+	var out []Elt
+	var symbol string
+
+	for _, symbol = range r.order {
+		out = append(out, r.elts[symbol])
+	}
+	return out
+}
@@ -2,8 +2,10 @@ package shuntingyard
 
 import "context"
 import "fmt"
+import "io"
 import "os"
 import "strings"
+import "time"
 
 /* used as cache of Elt, prevent execution of function which return constants */
 type elt_cache struct {
@@ -13,6 +15,27 @@ type elt_cache struct {
 	output_types [][]Type
 	kind int
 	elt Elt
+	// the element pushes a copy of the stack entry slot
+	local bool
+	slot int
+	// name of the macro from which the element is inlined
+	macro string
+	// rpn length when the operator is pushed on the precedence stack,
+	// and its left operand is already in the rpn
+	base int
+	infix bool
+}
+
+/* build elt cache from element */
+func new_elt_cache(elt Elt)(*elt_cache) {
+	return &elt_cache{
+		precedence: elt.Precedence(),
+		associativity: elt.Associativity(),
+		input_types: elt.Input_types(),
+		output_types: elt.Output_types(),
+		kind: elt.Kind(),
+		elt: elt,
+	}
 }
 
 type Expr struct {
@@ -28,8 +51,33 @@ type Expr struct {
 	// expression representation
 	name_elements []string
 	name string
+	// implicit conversions inserted during Finalize
+	conversions *Conversions
+	// disable implicit conversions
+	strict bool
+	// warnings reported by Finalize
+	warnings []string
+	// operands skipped by their connective, indexed by first position
+	lazy map[int]*lazy_operand
+	// macro whose body is the expression
+	macro *Macro
+	// construction mode, set by the first Append or Push
+	mode int
+	// Append state: the last element ends an operand, and the operands
+	// which can be juxtaposed for the prefix operators of each group
+	operand bool
+	slots []syntax_slot
+	// appended or pushed elements, replayed by Rewind
+	elements []Elt
 }
 
+/* construction modes of the expressions */
+const (
+	mode_none = iota
+	mode_append
+	mode_push
+)
+
 /* Implement Elt interface for Expr expression, except Execute which is located below */
 func (e *Expr) Precedence()(int) {
 	return 0
@@ -60,47 +108,77 @@ func (e *Expr)Set_name(n string) {
 	e.name = n
 }
 
-func (e *Expr)dump(level int)() {
+// Return the warnings reported by Finalize
+func (e *Expr)Warnings()([]string) {
+	return e.warnings
+}
+
+func (e *Expr)dump(w io.Writer, level int)() {
 	var ec *elt_cache
 	var ex *Expr
+	var ref *macro_ref
 	var ok bool
 
-	fmt.Fprintf(os.Stderr, "%s[%s]:\n", strings.Repeat("|   ", level - 1), e.String())
+	fmt.Fprintf(w, "%s[%s]:\n", strings.Repeat("|   ", level - 1), e.String())
 	for _, ec = range e.rpn {
 		ex, ok = ec.elt.(*Expr)
 		if ok {
-			ex.dump(level + 1)
-		} else {
-			fmt.Fprintf(os.Stderr, "%s%s\n", strings.Repeat("|   ", level), ec.elt.String())
+			ex.dump(w, level + 1)
+			continue
+		}
+		ref, ok = ec.elt.(*macro_ref)
+		if ok {
+			ref.m.body.dump(w, level + 1)
+			continue
 		}
+		if ec.macro != "" {
+			fmt.Fprintf(w, "%s%s <%s>\n", strings.Repeat("|   ", level), ec.elt.String(), ec.macro)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat("|   ", level), ec.elt.String())
 	}
 }
 
+// Dump the rpn on stderr. The nested expressions and the macros called
+// by reference are shown with their name, the elements inlined from a
+// macro are followed by the name of the macro.
 func (e *Expr)Dump()() {
-	e.dump(1)
+	e.dump(os.Stderr, 1)
+}
+
+// Like Dump, but write to w
+func (e *Expr)Fdump(w io.Writer)() {
+	e.dump(w, 1)
 }
 
 // Append element to the expression using shuntingyard algorithm
 func (e *Expr)Append(elt Elt)(error) {
 	var ec *elt_cache
 	var ec_browse *elt_cache
+	var err error
 
 	if e.done {
 		return fmt.Errorf("Expression already finalized")
 	}
+	if e.mode == mode_push {
+		return fmt.Errorf("Expression built with Push, can't Append %q", elt.String())
+	}
 
 	/* convert to elt cache */
-	ec = &elt_cache{
-		precedence: elt.Precedence(),
-		associativity: elt.Associativity(),
-		input_types: elt.Input_types(),
-		output_types: elt.Output_types(),
-		kind: elt.Kind(),
-		elt: elt,
+	ec = new_elt_cache(elt)
+
+	/* check the element can follow the previous ones. the mode is
+	 * recorded only for the accepted elements.
+	 */
+	err = e.check_syntax(ec)
+	if err != nil {
+		return err
 	}
+	e.mode = mode_append
 
 	/* build name */
 	e.name_elements = append(e.name_elements, elt.String())
+	e.elements = append(e.elements, elt)
 
 	/* pass value */
 	if ec.kind == Kind_value {
@@ -175,6 +253,7 @@ func (e *Expr)Append(elt Elt)(error) {
 		}
 
 		/* push operator in the stack */
+		ec.base = len(e.rpn)
 		e.precedence_stack = append(e.precedence_stack, ec)
 		return nil
 	}
@@ -184,66 +263,242 @@ func (e *Expr)Append(elt Elt)(error) {
 
 // Just push element. This is not compatible with the Append function.
 // the Push function is used to build your own expression stack using
-// RPN order. Mixing Push and Append return undefined result.
+// RPN order. Push returns an error if the expression is built with Append,
+// and Append returns an error if the expression is built with Push.
 func (e *Expr)Push(elt Elt)(error) {
 	var ec *elt_cache
 
 	if e.done {
 		return fmt.Errorf("Expression already finalized")
 	}
+	if e.mode == mode_append {
+		return fmt.Errorf("Expression built with Append, can't Push %q", elt.String())
+	}
 
 	/* convert to elt cache */
-	ec = &elt_cache{
-		precedence: elt.Precedence(),
-		associativity: elt.Associativity(),
-		input_types: elt.Input_types(),
-		output_types: elt.Output_types(),
-		kind: elt.Kind(),
-		elt: elt,
-	}
+	ec = new_elt_cache(elt)
 
 	/* build name */
 	e.name_elements = append(e.name_elements, elt.String())
+	e.elements = append(e.elements, elt)
 
 	/* push value */
 	e.rpn = append(e.rpn, ec)
+	e.mode = mode_push
 
 	return nil
 }
 
-/* all provided type must be found in required type */
+/* all provided type must be found in required type, or must be assignable to one required type */
 func Has_compat(provide []Type, require []Type)(bool) {
 	var provided_type Type
 	var required_type Type
+	var found bool
 
 	if len(provide) == 0 || len(require) == 0 {
 		return false
 	}
 
 	for _, provided_type = range provide {
+		found = false
 		for _, required_type = range require {
-			if provided_type == required_type {
+			if Is_assignable(provided_type, required_type) {
+				found = true
 				break
 			}
 		}
-		if provided_type != required_type {
+		if !found {
 			return false
 		}
 	}
 	return true
 }
 
-func (e *Expr)Finalize()(error) {
-	var ec_browse *elt_cache
-	var stack_types [][]Type
+/* check inputs of the element at position pos of the rpn against the
+ * simulated type stack, and update the stack with its outputs.
+ */
+func (e *Expr)check_elt(c *type_checker, pos int)(error) {
+	var ec *elt_cache
 	var i int
 	var stack_index int
+	var err error
+	var ok bool
+	var tf Type_function
+	var output_types [][]Type
+	var conv *conversion_elt
+	var nr Null_rejecter
+	var stack_types [][]Type
+	var key interface{}
+	var narrowed []Type
+	var when_true narrowing
+	var when_false narrowing
+
+	ec = e.rpn[pos]
+	c.enter(ec)
+	stack_types = c.types
+
+	/* the local reference has the type of the referenced entry */
+	if ec.local {
+		ec.output_types = [][]Type{stack_types[ec.slot]}
+	}
+
+	/* resolve overloaded element */
+	_, ok = ec.elt.(*Overload)
+	if ok {
+		ec, err = resolve_overload(ec, stack_types)
+		if err != nil {
+			return err
+		}
+		e.rpn[pos] = ec
+	}
+
+	/* check number of inputs */
+	if len(stack_types) < len(ec.input_types) {
+		return fmt.Errorf("Inconsistent expression, need %d entries, only %d available at symbol %q",
+		                       len(ec.input_types), len(stack_types), ec.elt.String())
+	}
+
+	/* check types of generic inputs, and bind the output types */
+	stack_index = len(stack_types) - len(ec.input_types)
+	if types_have_var(ec.input_types) {
+		output_types, err = unify_types(stack_types[stack_index:], ec.input_types, ec.output_types)
+		if err != nil {
+			return fmt.Errorf("Inconsistent expression, %q %s", ec.elt.String(), err.Error())
+		}
+		ec.output_types = output_types
+	}
+
+	/* check types of inputs */
+	for i = 0; i < len(ec.input_types) && !types_have_var(ec.input_types); i++ {
+		if !Has_compat(stack_types[stack_index + i], ec.input_types[i]) {
+
+			/* try to insert implicit conversion before the element,
+			 * and check the conversion in place of the element.
+			 */
+			if e.conversions != nil && !e.strict {
+				conv = e.conversions.plan(stack_types[stack_index:], i, ec.input_types[i])
+				if conv != nil {
+					e.rpn = append(e.rpn[:pos], append([]*elt_cache{new_elt_cache(conv)}, e.rpn[pos:]...)...)
+					return e.check_elt(c, pos)
+				}
+			}
+
+			return fmt.Errorf("Inconsistent expression, %q needs %s, got %s",
+			                       ec.elt.String(), Type_desc(ec.input_types[i]),
+			                       Type_desc(stack_types[stack_index + i]))
+		}
+	}
+
+	/* warn when nullable operands reach an element which rejects null */
+	nr, ok = ec.elt.(Null_rejecter)
+	if ok && nr.Rejects_null() {
+		for i = stack_index; i < len(stack_types); i++ {
+			if Is_nullable(stack_types[i]) {
+				e.warnings = append(e.warnings, fmt.Sprintf("Nullable value %s reaches non-null %q at position %d",
+				                                            Type_desc(stack_types[i]), ec.elt.String(), pos))
+			}
+		}
+	}
+
+	/* compute output types from input types */
+	tf, ok = ec.elt.(Type_function)
+	if ok {
+		output_types, err = tf.Infer_output_types(stack_types[stack_index:])
+		if err != nil {
+			return fmt.Errorf("Inconsistent expression, %q: %s", ec.elt.String(), err.Error())
+		}
+		if len(output_types) != len(ec.output_types) {
+			return fmt.Errorf("Inconsistent expression, %q infers %d outputs, declares %d",
+			                       ec.elt.String(), len(output_types), len(ec.output_types))
+		}
+		for i = range output_types {
+			if !Has_compat(output_types[i], ec.output_types[i]) {
+				return fmt.Errorf("Inconsistent expression, %q infers %s, declares %s",
+				                       ec.elt.String(), Type_desc(output_types[i]),
+				                       Type_desc(ec.output_types[i]))
+			}
+		}
+		ec.output_types = output_types
+	}
+
+	/* a variable checked by a guard has the narrowed types */
+	key = var_key(ec)
+	if key != nil {
+		narrowed = c.narrowed(key)
+		if narrowed != nil {
+			narrowed = intersect_types(ec.output_types[0], narrowed)
+			if len(narrowed) == 0 {
+				return fmt.Errorf("Inconsistent expression, %q can't be %s at position %d",
+				                  ec.elt.String(), Type_desc(c.narrowed(key)), pos)
+			}
+			ec.output_types = [][]Type{narrowed}
+		}
+	}
+
+	/* replace the inputs by the outputs in the stack */
+	when_true, when_false = c.facts(ec, stack_index)
+	c.pop(len(ec.input_types))
+	for i = range ec.output_types {
+		c.push(ec.output_types[i], key, when_true, when_false)
+	}
+
+	return nil
+}
+
+/* simulate the types of the rpn, return the type stack. the rpn may be
+ * modified by the macro expansion and the conversions.
+ */
+func (e *Expr)check_types()(*type_checker, error) {
+	var c *type_checker
+	var value_type []Type
+	var i int
+	var err error
+
+	/* push inputs in the type_stack */
+	c = &type_checker{}
+	for _, value_type = range e.input_types {
+		c.push(value_type, nil, nil, nil)
+	}
+	err = e.expand_macros()
+	if err != nil {
+		return nil, err
+	}
+	err = e.bind_locals()
+	if err != nil {
+		return nil, err
+	}
+	c.declare_scopes(e.rpn, len(e.input_types))
+
+	/* check the returned result. the rpn length is evaluated on each
+	 * loop because check_elt may insert conversions.
+	 */
+	for i = 0; i < len(e.rpn); i++ {
+		err = e.check_elt(c, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (e *Expr)Finalize()(error) {
+	var ec_browse *elt_cache
+	var c *type_checker
 	var value_type []Type
+	var err error
 
 	if e.done {
 		return fmt.Errorf("Expression already finalized")
 	}	
 
+	/* the last operator needs its operand, except if it applies to the inputs */
+	if e.mode == mode_append && !e.operand && len(e.input_types) == 0 {
+		if len(e.name_elements) == 0 {
+			return fmt.Errorf("Syntax error, missing operand")
+		}
+		return fmt.Errorf("Syntax error, missing operand after %q", e.name_elements[len(e.name_elements) - 1])
+	}
+
 	/* flush the stack */
 	for {
 
@@ -265,39 +520,14 @@ func (e *Expr)Finalize()(error) {
 		e.precedence_stack = e.precedence_stack[:len(e.precedence_stack) - 1]
 	}
 
-	/* push inputs in the type_stack */
-	for _, value_type = range e.input_types {
-		stack_types = append(stack_types, value_type)
-	}
-
-	/* check the returned result */
-	for _, ec_browse = range e.rpn {
-
-		/* check number of inputs */
-		if len(stack_types) < len(ec_browse.input_types) {
-			return fmt.Errorf("Inconsistent expression, need %d entries, only %d available at symbol %q",
-			                  len(ec_browse.input_types), len(stack_types), ec_browse.elt.String())
-		}
-
-		/* check types of inputs */
-		stack_index = len(stack_types) - len(ec_browse.input_types)
-		for i = 0; i < len(ec_browse.input_types); i++ {
-			if !Has_compat(stack_types[stack_index + i], ec_browse.input_types[i]) {
-				return fmt.Errorf("Inconsistent expression, %q needs %s, got %s",
-				                  ec_browse.elt.String(), Type_desc(ec_browse.input_types[i]),
-				                  Type_desc(stack_types[stack_index + i]))
-			}
-		}
-
-		/* pop entries from stack */
-		stack_types = stack_types[:stack_index]
-
-		/* push output in stack */
-		stack_types = append(stack_types, ec_browse.output_types...)
+	c, err = e.check_types()
+	if err != nil {
+		return err
 	}
+	e.lazy = lazy_operands(e.rpn, len(e.input_types))
 
 	/* store kind of returned value */
-	for _, value_type = range stack_types {
+	for _, value_type = range c.types {
 		e.output_types = append(e.output_types, value_type)
 	}
 
@@ -311,28 +541,105 @@ func (e *Expr)Finalize()(error) {
 	return nil
 }
 
+/* check values returned by an element against its declared output types */
+func check_outputs(ec *elt_cache, pos int, val []Value)(error) {
+	var i int
+	var v Value
+
+	if len(val) != len(ec.output_types) {
+		return fmt.Errorf("Contract violation, %q at position %d returns %d values, declares %d",
+		                  ec.elt.String(), pos, len(val), len(ec.output_types))
+	}
+	for i, v = range val {
+		if v == nil {
+			return fmt.Errorf("Contract violation, %q at position %d returns nil value as output #%d",
+			                  ec.elt.String(), pos, i)
+		}
+		if !Has_compat([]Type{v.Type()}, ec.output_types[i]) {
+			return fmt.Errorf("Contract violation, %q at position %d declares %s as output #%d, got %s",
+			                  ec.elt.String(), pos, Type_desc(ec.output_types[i]), i,
+			                  Type_string(v.Type()))
+		}
+	}
+	return nil
+}
+
+/* execute element at position pos of the rpn, using the top of the
+ * stack as inputs. return the new stack.
+ */
+func (e *Expr)exec_elt(ctx context.Context, opts *exec_opts, pos int, stack []Value)([]Value, error) {
+	var ec *elt_cache
+	var in []Value
+	var val []Value
+	var err error
+	var start time.Time
+
+	ec = e.rpn[pos]
+	if len(stack) < len(ec.input_types) {
+		return nil, fmt.Errorf("%q needs %d elements, only %d available",
+		                       ec.elt.String(), len(ec.input_types), len(stack))
+	}
+	in = stack[len(stack) - len(ec.input_types):]
+	if opts.tracer != nil {
+		opts.tracer.Before(e, pos, ec.elt, in)
+		start = time.Now()
+	}
+	if ec.local {
+		val = []Value{stack[ec.slot]}
+	} else {
+		val, err = ec.elt.Execute(ctx, in)
+	}
+	if err == nil && opts.checked {
+		err = check_outputs(ec, pos, val)
+	}
+	if opts.tracer != nil {
+		opts.tracer.After(e, pos, ec.elt, in, val, err, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+	stack = stack[:len(stack) - len(ec.input_types)]
+	stack = append(stack, val...)
+	return stack, nil
+}
+
 /* part of implementation of Elt interface for Expr expression */
 func (e *Expr)Execute(ctx context.Context, in []Value)([]Value, error) {
 	var stack []Value
-	var ec *elt_cache
-	var val []Value
+	var pos int
+	var next int
+	var skip bool
 	var err error
+	var opts exec_opts
+	var start time.Time
+
+	opts = get_exec_opts(ctx)
+
+	if opts.tracer != nil {
+		opts.tracer.Enter(e, in)
+		start = time.Now()
+	}
 
 	/* push input value in the stack */
 	stack = append(stack, in...)
 
-	for _, ec = range e.rpn {
-		if len(stack) < len(ec.input_types) {
-			return nil, fmt.Errorf("%q needs %d elements, only %d available",
-			                       ec.elt.String(), len(ec.input_types), len(stack))
+	for pos = 0; pos < len(e.rpn); pos++ {
+		next, stack, skip = e.skip_operand(&opts, pos, stack)
+		if skip {
+			pos = next - 1
+			continue
 		}
-		val, err = ec.elt.Execute(ctx, stack[len(stack) - len(ec.input_types):])
+		stack, err = e.exec_elt(ctx, &opts, pos, stack)
 		if err != nil {
-			return nil, err
+			break
 		}
-		stack = stack[:len(stack) - len(ec.input_types)]
-		stack = append(stack, val...)
 	}
 
+	if opts.tracer != nil {
+		opts.tracer.Leave(e, stack, err, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
 	return stack, nil
 }
@@ -0,0 +1,82 @@
+package shuntingyard
+
+import "context"
+
+/* element reordering the top entries of the stack. output i is the
+ * input order[i].
+ */
+type stack_op struct {
+	symbol string
+	input_types [][]Type
+	output_types [][]Type
+	order []int
+}
+
+func (s *stack_op)Precedence()(int) {
+	return Precedence_member
+}
+func (s *stack_op)Associativity()(int) {
+	return Associativity_left
+}
+func (s *stack_op)Kind()(int) {
+	return Kind_operator
+}
+func (s *stack_op)String()(string) {
+	return s.symbol
+}
+func (s *stack_op)Input_types()([][]Type) {
+	return s.input_types
+}
+func (s *stack_op)Output_types()([][]Type) {
+	return s.output_types
+}
+func (s *stack_op)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var out []Value
+	var i int
+
+	out = make([]Value, len(s.order))
+	for i = range s.order {
+		out[i] = in[s.order[i]]
+	}
+	return out, nil
+}
+
+/* build stack element consuming len(vars) entries, the types of the
+ * outputs are the types of the inputs designated by order.
+ */
+func new_stack_op(symbol string, vars []*Type_var, order ...int)(*stack_op) {
+	var s *stack_op
+	var v *Type_var
+	var i int
+
+	s = &stack_op{
+		symbol: symbol,
+		order: order,
+	}
+	for _, v = range vars {
+		s.input_types = append(s.input_types, []Type{v})
+	}
+	for _, i = range order {
+		s.output_types = append(s.output_types, []Type{vars[i]})
+	}
+	return s
+}
+
+var stack_a *Type_var = New_type_var("A")
+var stack_b *Type_var = New_type_var("B")
+
+// Stack elements for the expressions built with Push. Finalize gives
+// the types of their inputs to their outputs, so they reuse or reorder
+// values without computing them again: "x dup *" is x * x.
+
+// Duplicate the top entry: a -> a a
+var Dup Elt = new_stack_op("dup", []*Type_var{stack_a}, 0, 0)
+
+// Exchange the two top entries: a b -> b a
+var Swap Elt = new_stack_op("swap", []*Type_var{stack_a, stack_b}, 1, 0)
+
+// Remove the top entry: a ->
+var Drop Elt = new_stack_op("drop", []*Type_var{stack_a})
+
+// Copy the entry below the top: a b -> a b a
+var Over Elt = new_stack_op("over", []*Type_var{stack_a, stack_b}, 0, 1, 0)
@@ -0,0 +1,56 @@
+package shuntingyard
+
+import "testing"
+
+func Test_stack(t *testing.T) {
+	var sub Elt
+	var mul Elt
+	var count *count_elt
+	var three Elt
+	var word Elt
+	var e *Expr
+	var v []Value
+	var c struct {
+		elts []Elt
+		expect string
+		typ string
+	}
+
+	sub = Binary("-", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a - b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	three = Constant("3", int64(3))
+	word = Constant("w", "w")
+	count = &count_elt{}
+
+	for _, c = range []struct {
+		elts []Elt
+		expect string
+		typ string
+	}{
+		{[]Elt{count, Dup, mul}, "4", "int64"},
+		{[]Elt{count, three, Swap, sub}, "1", "int64"},
+		{[]Elt{count, three, sub}, "-1", "int64"},
+		{[]Elt{count, three, Drop}, "2", "int64"},
+		{[]Elt{count, three, Over, sub, mul}, "2", "int64"},
+		{[]Elt{word, count, Swap}, "w", "int64, string"},
+		{[]Elt{word, count, Over}, "w", "string, int64, string"},
+	} {
+		count.calls = 0
+		e, v = push_execute(t, c.elts, c.typ)
+		if v != nil && (v[len(v) - 1].Descr() != c.expect || count.calls != 1) {
+			t.Errorf("Expect %s computing count once for %q, got %s computing count %d times",
+			         c.expect, e.String(), v[len(v) - 1].Descr(), count.calls)
+		}
+	}
+
+	/* the stack must hold the entries, and the types follow the entries */
+	for _, c.elts = range [][]Elt{
+		{Dup},
+		{count, Swap},
+		{count, Over},
+		{word, count, Swap, sub},
+		{count, Drop, Drop},
+	} {
+		push_reject(t, c.elts)
+	}
+}
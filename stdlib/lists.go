@@ -0,0 +1,197 @@
+package stdlib
+
+import "context"
+import "fmt"
+
+import "github.com/thierry-f-78/go-shuntingyard"
+
+var type_u *shuntingyard.Type_var = shuntingyard.New_type_var("U")
+
+/* higher-order operators on lists, the last operand is a lambda */
+type list_op struct {
+	symbol string
+	input_types [][]shuntingyard.Type
+	output_types [][]shuntingyard.Type
+	fn func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error)
+}
+
+func (o *list_op)Precedence()(int) {
+	return Precedence_unary
+}
+func (o *list_op)Associativity()(int) {
+	return shuntingyard.Associativity_right
+}
+func (o *list_op)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (o *list_op)String()(string) {
+	return o.symbol
+}
+func (o *list_op)Input_types()([][]shuntingyard.Type) {
+	return o.input_types
+}
+func (o *list_op)Output_types()([][]shuntingyard.Type) {
+	return o.output_types
+}
+func (o *list_op)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	var l *shuntingyard.List_value
+	var f *shuntingyard.Lambda_value
+	var v shuntingyard.Value
+	var ok bool
+	var err error
+
+	l, ok = in[0].(*shuntingyard.List_value)
+	if !ok {
+		return nil, fmt.Errorf("%q needs list, got %s", o.symbol, shuntingyard.Type_string(in[0].Type()))
+	}
+	f, ok = in[len(in) - 1].(*shuntingyard.Lambda_value)
+	if !ok {
+		return nil, fmt.Errorf("%q needs lambda, got %s", o.symbol, shuntingyard.Type_string(in[len(in) - 1].Type()))
+	}
+	v, err = o.fn(l, in, f)
+	if err != nil {
+		return nil, err
+	}
+	return []shuntingyard.Value{v}, nil
+}
+
+/* call the predicate f on each item until it returns stop */
+func until(l *shuntingyard.List_value, f *shuntingyard.Lambda_value, stop bool)(bool, error) {
+	var item shuntingyard.Value
+	var v shuntingyard.Value
+	var b bool
+	var err error
+
+	for _, item = range l.Items {
+		v, err = f.Call(item)
+		if err != nil {
+			return false, err
+		}
+		b, err = shuntingyard.Get[bool](v)
+		if err != nil {
+			return false, err
+		}
+		if b == stop {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var list_t []shuntingyard.Type = []shuntingyard.Type{shuntingyard.List_type(type_t)}
+var predicate_t []shuntingyard.Type = []shuntingyard.Type{shuntingyard.Func_type([]shuntingyard.Type{type_t}, Type_bool)}
+
+// Higher-order operators. Their operands are usually groups, the last one
+// is a lambda: "any (items) (lambda)".
+
+// any(list<T>, func<T, bool>) -> bool, true if the lambda is true for one item
+var Any shuntingyard.Elt = &list_op{
+	symbol: "any",
+	input_types: [][]shuntingyard.Type{list_t, predicate_t},
+	output_types: [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}},
+	fn: func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error) {
+		var found bool
+		var err error
+
+		found, err = until(l, f, true)
+		return Bool(found), err
+	},
+}
+
+// all(list<T>, func<T, bool>) -> bool, true if the lambda is true for all items
+var All shuntingyard.Elt = &list_op{
+	symbol: "all",
+	input_types: [][]shuntingyard.Type{list_t, predicate_t},
+	output_types: [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}},
+	fn: func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error) {
+		var found bool
+		var err error
+
+		found, err = until(l, f, false)
+		return Bool(!found), err
+	},
+}
+
+// map(list<T>, func<T, U>) -> list<U>, the results of the lambda on each item
+var Map shuntingyard.Elt = &list_op{
+	symbol: "map",
+	input_types: [][]shuntingyard.Type{
+		list_t,
+		[]shuntingyard.Type{shuntingyard.Func_type([]shuntingyard.Type{type_t}, type_u)},
+	},
+	output_types: [][]shuntingyard.Type{[]shuntingyard.Type{shuntingyard.List_type(type_u)}},
+	fn: func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error) {
+		var args []shuntingyard.Type
+		var out *shuntingyard.List_value
+		var item shuntingyard.Value
+		var v shuntingyard.Value
+		var err error
+
+		args = f.Type().(*shuntingyard.Param_type).Args()
+		out = shuntingyard.New_list_value(args[len(args) - 1], nil)
+		for _, item = range l.Items {
+			v, err = f.Call(item)
+			if err != nil {
+				return nil, err
+			}
+			out.Items = append(out.Items, v)
+		}
+		return out, nil
+	},
+}
+
+// filter(list<T>, func<T, bool>) -> list<T>, the items for which the lambda is true
+var Filter shuntingyard.Elt = &list_op{
+	symbol: "filter",
+	input_types: [][]shuntingyard.Type{list_t, predicate_t},
+	output_types: [][]shuntingyard.Type{list_t},
+	fn: func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error) {
+		var out *shuntingyard.List_value
+		var item shuntingyard.Value
+		var v shuntingyard.Value
+		var b bool
+		var err error
+
+		out = shuntingyard.New_list_value(l.Elem(), nil)
+		for _, item = range l.Items {
+			v, err = f.Call(item)
+			if err != nil {
+				return nil, err
+			}
+			b, err = shuntingyard.Get[bool](v)
+			if err != nil {
+				return nil, err
+			}
+			if b {
+				out.Items = append(out.Items, item)
+			}
+		}
+		return out, nil
+	},
+}
+
+// reduce(list<T>, U, func<U, T, U>) -> U, fold the items with the lambda
+// starting from the initial value
+var Reduce shuntingyard.Elt = &list_op{
+	symbol: "reduce",
+	input_types: [][]shuntingyard.Type{
+		list_t,
+		[]shuntingyard.Type{type_u},
+		[]shuntingyard.Type{shuntingyard.Func_type([]shuntingyard.Type{type_u, type_t}, type_u)},
+	},
+	output_types: [][]shuntingyard.Type{[]shuntingyard.Type{type_u}},
+	fn: func(l *shuntingyard.List_value, in []shuntingyard.Value, f *shuntingyard.Lambda_value)(shuntingyard.Value, error) {
+		var acc shuntingyard.Value
+		var item shuntingyard.Value
+		var err error
+
+		acc = in[1]
+		for _, item = range l.Items {
+			acc, err = f.Call(acc, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return acc, nil
+	},
+}
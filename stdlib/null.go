@@ -0,0 +1,321 @@
+package stdlib
+
+import "context"
+import "fmt"
+
+import "github.com/thierry-f-78/go-shuntingyard"
+
+// Null semantics of the standard operators. Whatever the semantic, "=="
+// and "!=" compare the nil values, like the Go operators: nil == nil is
+// true and 1 == nil is false. The comparisons with nil narrow the checked
+// variable, "x != nil and x > 3" is valid with the semantic Null_strict.
+const (
+	// the operators doesn't accept nil, the type checker rejects the
+	// nullable operands
+	Null_strict = iota
+	// the operators accept nil, but fail when they receive it. Finalize
+	// warns when a nullable operand reaches an operator
+	Null_error
+	// the operators return nil when they receive nil
+	Null_propagate
+	// SQL semantic: nil is unknown, "false and unknown" is false, "true or
+	// unknown" is true, and the other operators return unknown
+	Null_unknown
+)
+
+/* operator handling nil operands according with the null semantic */
+type null_elt struct {
+	elt shuntingyard.Elt
+	semantic int
+	equality bool
+	logic bool
+	input_types [][]shuntingyard.Type
+	output_types [][]shuntingyard.Type
+}
+
+/* add nil to each alternative which is not nullable */
+func add_nil(types [][]shuntingyard.Type)([][]shuntingyard.Type) {
+	var out [][]shuntingyard.Type
+	var alt []shuntingyard.Type
+
+	for _, alt = range types {
+		if shuntingyard.Is_nullable(alt) {
+			out = append(out, alt)
+			continue
+		}
+		out = append(out, append(append([]shuntingyard.Type(nil), alt...), Type_nil))
+	}
+	return out
+}
+
+/* wrap the operator elt according with the null semantic */
+func null_wrap(elt shuntingyard.Elt, semantic int)(shuntingyard.Elt) {
+	var n *null_elt
+	var equality bool
+
+	equality = elt.String() == "==" || elt.String() == "!="
+	if semantic == Null_strict && !equality {
+		return elt
+	}
+	n = &null_elt{
+		elt: elt,
+		semantic: semantic,
+		equality: equality,
+		logic: elt == And || elt == Or || elt == Not,
+		input_types: add_nil(elt.Input_types()),
+		output_types: elt.Output_types(),
+	}
+	if !n.equality && (semantic == Null_propagate || semantic == Null_unknown) {
+		n.output_types = add_nil(elt.Output_types())
+	}
+	if n.equality {
+		return &null_equality{null_elt: n}
+	}
+	if n.logic {
+		return &null_logic{null_elt: n}
+	}
+	return n
+}
+
+func (n *null_elt)Precedence()(int) {
+	return n.elt.Precedence()
+}
+func (n *null_elt)Associativity()(int) {
+	return n.elt.Associativity()
+}
+func (n *null_elt)Kind()(int) {
+	return n.elt.Kind()
+}
+func (n *null_elt)String()(string) {
+	return n.elt.String()
+}
+func (n *null_elt)Input_types()([][]shuntingyard.Type) {
+	return n.input_types
+}
+func (n *null_elt)Output_types()([][]shuntingyard.Type) {
+	return n.output_types
+}
+
+/* the outputs are nullable only if one input is nullable */
+func (n *null_elt)Infer_output_types(in [][]shuntingyard.Type)([][]shuntingyard.Type, error) {
+	var alt []shuntingyard.Type
+
+	for _, alt = range in {
+		if shuntingyard.Is_nullable(alt) {
+			return n.output_types, nil
+		}
+	}
+	return n.elt.Output_types(), nil
+}
+
+func (n *null_elt)Rejects_null()(bool) {
+	return n.semantic == Null_error && !n.equality
+}
+
+/* return the boolean inputs, nil for unknown values */
+func tristate(in []shuntingyard.Value)([]*bool) {
+	var out []*bool
+	var v shuntingyard.Value
+	var b bool
+	var err error
+
+	for _, v = range in {
+		b, err = shuntingyard.Get[bool](v)
+		if err != nil {
+			out = append(out, nil)
+			continue
+		}
+		out = append(out, &b)
+	}
+	return out
+}
+
+/* three-valued logic */
+func (n *null_elt)logic_execute(in []shuntingyard.Value)(shuntingyard.Value) {
+	var b *bool
+	var unknown bool
+	var absorb bool
+
+	if n.elt == Not {
+		return Nil
+	}
+	absorb = n.elt == Or
+	for _, b = range tristate(in) {
+		if b == nil {
+			unknown = true
+		} else if *b == absorb {
+			return Bool(absorb)
+		}
+	}
+	if unknown {
+		return Nil
+	}
+	return Bool(!absorb)
+}
+
+func (n *null_elt)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	var v shuntingyard.Value
+	var nils int
+	var out []shuntingyard.Value
+	var i int
+
+	for _, v = range in {
+		if v != nil && v.Type() == Type_nil {
+			nils++
+		}
+	}
+	if nils == 0 {
+		return n.elt.Execute(ctx, in)
+	}
+
+	switch {
+	case n.equality:
+		return []shuntingyard.Value{Bool((nils == len(in)) == (n.elt.String() == "=="))}, nil
+	case n.semantic == Null_error:
+		return nil, fmt.Errorf("%q receives nil operand", n.elt.String())
+	case n.semantic == Null_unknown && n.logic:
+		return []shuntingyard.Value{n.logic_execute(in)}, nil
+	}
+	for i = 0; i < len(n.output_types); i++ {
+		out = append(out, Nil)
+	}
+	return out, nil
+}
+
+/* logical operators are determined by their absorbing operand */
+func (n *null_elt)Decisive(in []shuntingyard.Value, out []shuntingyard.Value)([]int) {
+	var d shuntingyard.Decider
+	var b *bool
+	var all []int
+	var i int
+	var ok bool
+
+	if n.logic && n.elt != Not {
+		for i, b = range tristate(in) {
+			if b != nil && *b == (n.elt == Or) && out[0].Type() != Type_nil {
+				return []int{i}
+			}
+			all = append(all, i)
+		}
+		return all
+	}
+	d, ok = n.elt.(shuntingyard.Decider)
+	if ok {
+		return d.Decisive(in, out)
+	}
+	for i = range in {
+		all = append(all, i)
+	}
+	return all
+}
+
+/* logical operator handling nil operands */
+type null_logic struct {
+	*null_elt
+}
+
+func (n *null_logic)Connective()(int) {
+	return n.elt.(shuntingyard.Connective).Connective()
+}
+
+/* the nil operand never determines the result */
+func (n *null_logic)Skip(index int, previous []shuntingyard.Value)(bool) {
+	return n.elt.(shuntingyard.Connective).Skip(index, previous)
+}
+
+/* return true if the operand is the nil literal */
+func is_nil_type(types []shuntingyard.Type)(bool) {
+	return len(types) == 1 && types[0] == Type_nil
+}
+
+/* "==" and "!=", the comparisons with nil are guards */
+type null_equality struct {
+	*null_elt
+}
+
+func (n *null_equality)Narrow(in [][]shuntingyard.Type, result bool)([][]shuntingyard.Type) {
+	var out [][]shuntingyard.Type
+	var nil_equal bool
+
+	if len(in) != 2 {
+		return nil
+	}
+	nil_equal = result == (n.elt.String() == "==")
+	out = make([][]shuntingyard.Type, 2)
+	if is_nil_type(in[1]) {
+		out[0] = narrow_nil(in[0], nil_equal)
+	}
+	if is_nil_type(in[0]) {
+		out[1] = narrow_nil(in[1], nil_equal)
+	}
+	return out
+}
+
+/* return the types of the value which is nil or not */
+func narrow_nil(types []shuntingyard.Type, is_nil bool)([]shuntingyard.Type) {
+	if is_nil {
+		return []shuntingyard.Type{Type_nil}
+	}
+	return shuntingyard.Strip_null(types)
+}
+
+/* "==" and "!=" on two nil operands. The candidates on the nullable
+ * types all match nil == nil, this one is chosen because it is exact.
+ */
+type nil_compare struct {
+	symbol string
+}
+
+func (n *nil_compare)Precedence()(int) {
+	return Precedence_compare
+}
+func (n *nil_compare)Associativity()(int) {
+	return shuntingyard.Associativity_left
+}
+func (n *nil_compare)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (n *nil_compare)String()(string) {
+	return n.symbol
+}
+func (n *nil_compare)Input_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_nil}, []shuntingyard.Type{Type_nil}}
+}
+func (n *nil_compare)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}}
+}
+func (n *nil_compare)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	return []shuntingyard.Value{Bool(n.symbol == "==")}, nil
+}
+
+/* operator testing nil value */
+type is_nil struct {}
+
+func (n *is_nil)Precedence()(int) {
+	return Precedence_unary
+}
+func (n *is_nil)Associativity()(int) {
+	return shuntingyard.Associativity_right
+}
+func (n *is_nil)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (n *is_nil)String()(string) {
+	return "is_nil"
+}
+func (n *is_nil)Input_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_any}}
+}
+func (n *is_nil)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}}
+}
+func (n *is_nil)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	return []shuntingyard.Value{Bool(in[0].Type() == Type_nil)}, nil
+}
+
+func (n *is_nil)Narrow(in [][]shuntingyard.Type, result bool)([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{narrow_nil(in[0], result)}
+}
+
+// Prefix operator which returns true if its operand is nil
+var Is_nil shuntingyard.Elt = &is_nil{}
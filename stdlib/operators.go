@@ -0,0 +1,464 @@
+package stdlib
+
+import "bytes"
+import "context"
+import "fmt"
+import "strings"
+import "time"
+
+import "github.com/thierry-f-78/go-shuntingyard"
+
+// Conventional precedences of the standard operators
+const (
+	Precedence_or = 1
+	Precedence_and = 2
+	Precedence_not = 3
+	Precedence_compare = 4
+	Precedence_additive = 5
+	Precedence_multiplicative = 6
+	Precedence_unary = 7
+)
+
+/* group elements */
+type group struct {
+	symbol string
+	kind int
+}
+
+func (g *group)Precedence()(int) {
+	return 0
+}
+func (g *group)Associativity()(int) {
+	return 0
+}
+func (g *group)Kind()(int) {
+	return g.kind
+}
+func (g *group)String()(string) {
+	return g.symbol
+}
+func (g *group)Input_types()([][]shuntingyard.Type) {
+	return nil
+}
+func (g *group)Output_types()([][]shuntingyard.Type) {
+	return nil
+}
+func (g *group)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	return nil, fmt.Errorf("Group %q can't be executed", g.symbol)
+}
+
+// Open and close group elements
+var Open shuntingyard.Elt = &group{symbol: "(", kind: shuntingyard.Kind_group_open}
+var Close shuntingyard.Elt = &group{symbol: ")", kind: shuntingyard.Kind_group_close}
+
+// The nil literal
+var Nil_literal shuntingyard.Elt = &literal{symbol: "nil", value: Nil}
+
+/* logical operators, they implement shuntingyard.Decider */
+type logic struct {
+	symbol string
+	precedence int
+	associativity int
+	input_types [][]shuntingyard.Type
+	fn func(in []bool)(bool)
+}
+
+func (l *logic)Precedence()(int) {
+	return l.precedence
+}
+func (l *logic)Associativity()(int) {
+	return l.associativity
+}
+func (l *logic)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (l *logic)String()(string) {
+	return l.symbol
+}
+func (l *logic)Input_types()([][]shuntingyard.Type) {
+	return l.input_types
+}
+func (l *logic)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}}
+}
+func (l *logic)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	var b []bool
+	var v shuntingyard.Value
+	var x bool
+	var err error
+
+	/* the right operand is skipped when the left one determines the result */
+	if len(in) == 2 && l.Skip(1, in[:1]) {
+		return in[:1], nil
+	}
+	for _, v = range in {
+		x, err = shuntingyard.Get[bool](v)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, x)
+	}
+	return []shuntingyard.Value{Bool(l.fn(b))}, nil
+}
+
+func (l *logic)Connective()(int) {
+	switch l.symbol {
+	case "and":
+		return shuntingyard.Connective_and
+	case "or":
+		return shuntingyard.Connective_or
+	}
+	return shuntingyard.Connective_not
+}
+
+/* "false and x" and "true or x" don't evaluate x */
+func (l *logic)Skip(index int, previous []shuntingyard.Value)(bool) {
+	var x bool
+	var err error
+
+	if l.symbol == "not" {
+		return false
+	}
+	x, err = shuntingyard.Get[bool](previous[0])
+	if err != nil {
+		return false
+	}
+	return x == (l.symbol == "or")
+}
+
+/* "and" is determined by its first false input, "or" by its first true input */
+func (l *logic)Decisive(in []shuntingyard.Value, out []shuntingyard.Value)([]int) {
+	var result bool
+	var x bool
+	var all []int
+	var i int
+
+	result, _ = shuntingyard.Get[bool](out[0])
+	for i = range in {
+		x, _ = shuntingyard.Get[bool](in[i])
+		if (l.symbol == "and" && !x && !result) || (l.symbol == "or" && x && result) {
+			return []int{i}
+		}
+		all = append(all, i)
+	}
+	return all
+}
+
+/* conditional "if (cond) (then) (else)" */
+type conditional struct {}
+
+var type_t *shuntingyard.Type_var = shuntingyard.New_type_var("T")
+
+func (c *conditional)Precedence()(int) {
+	return Precedence_unary
+}
+func (c *conditional)Associativity()(int) {
+	return shuntingyard.Associativity_right
+}
+func (c *conditional)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (c *conditional)String()(string) {
+	return "if"
+}
+func (c *conditional)Input_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{
+		[]shuntingyard.Type{Type_bool},
+		[]shuntingyard.Type{type_t},
+		[]shuntingyard.Type{type_t},
+	}
+}
+func (c *conditional)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{type_t}}
+}
+func (c *conditional)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	var cond bool
+	var err error
+
+	cond, err = shuntingyard.Get[bool](in[0])
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return in[1:2], nil
+	}
+	return in[2:3], nil
+}
+func (c *conditional)Connective()(int) {
+	return shuntingyard.Connective_if
+}
+
+/* only the branch selected by the condition is evaluated */
+func (c *conditional)Skip(index int, previous []shuntingyard.Value)(bool) {
+	var cond bool
+	var err error
+
+	cond, err = shuntingyard.Get[bool](previous[0])
+	if err != nil {
+		return false
+	}
+	return cond == (index == 2)
+}
+
+// Conditional returning its second operand if the first one is true, the
+// third one otherwise. Only the selected operand is evaluated. The operator
+// has the unary precedence, its operands are usually groups:
+// "if (x != nil) (x) (0)"
+var If shuntingyard.Elt = &conditional{}
+
+/* integer division returning the quotient and the remainder */
+type divmod struct {}
+
+func (d *divmod)Precedence()(int) {
+	return Precedence_unary
+}
+func (d *divmod)Associativity()(int) {
+	return shuntingyard.Associativity_right
+}
+func (d *divmod)Kind()(int) {
+	return shuntingyard.Kind_operator
+}
+func (d *divmod)String()(string) {
+	return "divmod"
+}
+func (d *divmod)Input_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{
+		[]shuntingyard.Type{Type_int64},
+		[]shuntingyard.Type{Type_int64},
+	}
+}
+func (d *divmod)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{
+		[]shuntingyard.Type{Type_int64},
+		[]shuntingyard.Type{Type_int64},
+	}
+}
+func (d *divmod)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	var a int64
+	var b int64
+	var err error
+
+	a, err = shuntingyard.Get[int64](in[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err = shuntingyard.Get[int64](in[1])
+	if err != nil {
+		return nil, err
+	}
+	if b == 0 {
+		return nil, fmt.Errorf("Division by zero")
+	}
+	return []shuntingyard.Value{Int64(a / b), Int64(a % b)}, nil
+}
+
+// Integer division returning two values, the quotient and the remainder.
+// The operator has the unary precedence, its operands are usually groups,
+// its results are usually folded in a tuple: "tuple (divmod (7) (2))"
+var Divmod shuntingyard.Elt = &divmod{}
+
+var bool_bool [][]shuntingyard.Type = [][]shuntingyard.Type{
+	[]shuntingyard.Type{Type_bool},
+	[]shuntingyard.Type{Type_bool},
+}
+
+// Logical operators
+var And shuntingyard.Elt = &logic{
+	symbol: "and",
+	precedence: Precedence_and,
+	associativity: shuntingyard.Associativity_left,
+	input_types: bool_bool,
+	fn: func(in []bool)(bool) { return in[0] && in[1] },
+}
+var Or shuntingyard.Elt = &logic{
+	symbol: "or",
+	precedence: Precedence_or,
+	associativity: shuntingyard.Associativity_left,
+	input_types: bool_bool,
+	fn: func(in []bool)(bool) { return in[0] || in[1] },
+}
+var Not shuntingyard.Elt = &logic{
+	symbol: "not",
+	precedence: Precedence_not,
+	associativity: shuntingyard.Associativity_right,
+	input_types: [][]shuntingyard.Type{[]shuntingyard.Type{Type_bool}},
+	fn: func(in []bool)(bool) { return !in[0] },
+}
+
+/* numeric types supporting arithmetic operators */
+type number interface {
+	~int64 | ~float64
+}
+
+/* types supporting comparison operators */
+type ordered interface {
+	~int64 | ~float64 | ~string
+}
+
+/* candidates indexed by symbol, in registration order */
+type candidates struct {
+	symbols []string
+	elts map[string][]shuntingyard.Elt
+}
+
+func (c *candidates)add(elt shuntingyard.Elt) {
+	if c.elts[elt.String()] == nil {
+		c.symbols = append(c.symbols, elt.String())
+	}
+	c.elts[elt.String()] = append(c.elts[elt.String()], elt)
+}
+
+func binary[A any, B any, R any](c *candidates, symbol string, precedence int, fn func(A, B)(R, error)) {
+	c.add(shuntingyard.Binary(symbol, precedence, shuntingyard.Associativity_left, fn))
+}
+
+func unary[A any, R any](c *candidates, symbol string, fn func(A)(R, error)) {
+	c.add(shuntingyard.Unary(symbol, Precedence_unary, shuntingyard.Associativity_right, fn))
+}
+
+func arithmetic[T number](c *candidates) {
+	binary(c, "+", Precedence_additive, func(a T, b T)(T, error) { return a + b, nil })
+	binary(c, "-", Precedence_additive, func(a T, b T)(T, error) { return a - b, nil })
+	binary(c, "*", Precedence_multiplicative, func(a T, b T)(T, error) { return a * b, nil })
+	unary(c, "neg", func(a T)(T, error) { return -a, nil })
+}
+
+func compare[T ordered](c *candidates) {
+	binary(c, "==", Precedence_compare, func(a T, b T)(bool, error) { return a == b, nil })
+	binary(c, "!=", Precedence_compare, func(a T, b T)(bool, error) { return a != b, nil })
+	binary(c, "<", Precedence_compare, func(a T, b T)(bool, error) { return a < b, nil })
+	binary(c, "<=", Precedence_compare, func(a T, b T)(bool, error) { return a <= b, nil })
+	binary(c, ">", Precedence_compare, func(a T, b T)(bool, error) { return a > b, nil })
+	binary(c, ">=", Precedence_compare, func(a T, b T)(bool, error) { return a >= b, nil })
+}
+
+/* build all the standard operators */
+func operators()(*candidates) {
+	var c *candidates
+
+	c = &candidates{
+		elts: make(map[string][]shuntingyard.Elt),
+	}
+
+	c.add(Or)
+	c.add(And)
+	c.add(Not)
+
+	arithmetic[int64](c)
+	arithmetic[float64](c)
+	binary(c, "/", Precedence_multiplicative, func(a int64, b int64)(int64, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("Division by zero")
+		}
+		return a / b, nil
+	})
+	binary(c, "/", Precedence_multiplicative, func(a float64, b float64)(float64, error) { return a / b, nil })
+	binary(c, "%", Precedence_multiplicative, func(a int64, b int64)(int64, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("Division by zero")
+		}
+		return a % b, nil
+	})
+
+	/* strings */
+	binary(c, "+", Precedence_additive, func(a string, b string)(string, error) { return a + b, nil })
+	binary(c, "contains", Precedence_compare, func(a string, b string)(bool, error) {
+		return strings.Contains(a, b), nil
+	})
+	binary(c, "starts_with", Precedence_compare, func(a string, b string)(bool, error) {
+		return strings.HasPrefix(a, b), nil
+	})
+	binary(c, "ends_with", Precedence_compare, func(a string, b string)(bool, error) {
+		return strings.HasSuffix(a, b), nil
+	})
+	unary(c, "len", func(a string)(int64, error) { return int64(len(a)), nil })
+	unary(c, "len", func(a []byte)(int64, error) { return int64(len(a)), nil })
+	unary(c, "lower", func(a string)(string, error) { return strings.ToLower(a), nil })
+	unary(c, "upper", func(a string)(string, error) { return strings.ToUpper(a), nil })
+
+	/* time and durations */
+	binary(c, "+", Precedence_additive, func(a time.Duration, b time.Duration)(time.Duration, error) { return a + b, nil })
+	binary(c, "+", Precedence_additive, func(a time.Time, b time.Duration)(time.Time, error) { return a.Add(b), nil })
+	binary(c, "-", Precedence_additive, func(a time.Duration, b time.Duration)(time.Duration, error) { return a - b, nil })
+	binary(c, "-", Precedence_additive, func(a time.Time, b time.Time)(time.Duration, error) { return a.Sub(b), nil })
+	binary(c, "-", Precedence_additive, func(a time.Time, b time.Duration)(time.Time, error) { return a.Add(-b), nil })
+	unary(c, "neg", func(a time.Duration)(time.Duration, error) { return -a, nil })
+
+	/* comparisons */
+	compare[int64](c)
+	compare[float64](c)
+	compare[string](c)
+	compare[time.Duration](c)
+	binary(c, "==", Precedence_compare, func(a bool, b bool)(bool, error) { return a == b, nil })
+	binary(c, "!=", Precedence_compare, func(a bool, b bool)(bool, error) { return a != b, nil })
+	binary(c, "==", Precedence_compare, func(a []byte, b []byte)(bool, error) { return bytes.Equal(a, b), nil })
+	binary(c, "!=", Precedence_compare, func(a []byte, b []byte)(bool, error) { return !bytes.Equal(a, b), nil })
+	binary(c, "==", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return a.Equal(b), nil })
+	binary(c, "!=", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return !a.Equal(b), nil })
+	binary(c, "<", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return a.Before(b), nil })
+	binary(c, "<=", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return !a.After(b), nil })
+	binary(c, ">", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return a.After(b), nil })
+	binary(c, ">=", Precedence_compare, func(a time.Time, b time.Time)(bool, error) { return !a.Before(b), nil })
+	c.add(&nil_compare{symbol: "=="})
+	c.add(&nil_compare{symbol: "!="})
+
+	return c
+}
+
+// Register the standard elements in r using the semantic Null_strict.
+func Register(r *shuntingyard.Registry)(error) {
+	return Register_null(r, Null_strict)
+}
+
+// Register the standard operators, the groups, the nil literal, the
+// conditional, divmod and the higher-order list operators in r. The operators
+// handle the nil operands according with the semantic, use Null_*. The
+// symbols with many implementations, like "+" on numbers, strings and
+// durations, are registered as shuntingyard.Overload.
+func Register_null(r *shuntingyard.Registry, semantic int)(error) {
+	var c *candidates
+	var symbol string
+	var elt shuntingyard.Elt
+	var elts []shuntingyard.Elt
+	var err error
+
+	c = operators()
+	for _, symbol = range c.symbols {
+		elts = nil
+		for _, elt = range c.elts[symbol] {
+			elts = append(elts, null_wrap(elt, semantic))
+		}
+		if len(elts) == 1 {
+			elt = elts[0]
+		} else {
+			elt, err = shuntingyard.New_overload(symbol, elts...)
+			if err != nil {
+				return err
+			}
+		}
+		err = r.Add(elt)
+		if err != nil {
+			return err
+		}
+	}
+	return r.Add(Open, Close, Nil_literal, Is_nil, If, Divmod, Any, All, Map, Filter, Reduce)
+}
+
+// Return registry containing the standard operators using the
+// semantic Null_strict
+func New_registry()(*shuntingyard.Registry) {
+	return New_registry_null(Null_strict)
+}
+
+// Return registry containing the standard operators using the null semantic
+func New_registry_null(semantic int)(*shuntingyard.Registry) {
+	var r *shuntingyard.Registry
+	var err error
+
+	r = shuntingyard.New_registry()
+	err = Register_null(r, semantic)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
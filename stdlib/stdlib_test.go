@@ -0,0 +1,535 @@
+package stdlib
+
+import "context"
+import "testing"
+import "time"
+
+import "github.com/thierry-f-78/go-shuntingyard"
+
+/* build expression from symbols of the registry and from values */
+func build(t *testing.T, r *shuntingyard.Registry, items ...interface{})(*shuntingyard.Expr, error) {
+	var e *shuntingyard.Expr
+	var item interface{}
+	var elt shuntingyard.Elt
+	var ok bool
+	var err error
+
+	e = shuntingyard.New(nil)
+	for _, item = range items {
+		switch item.(type) {
+		case string:
+			elt, ok = r.Get(item.(string))
+			if !ok {
+				t.Fatalf("Unknown symbol %q", item)
+			}
+		case shuntingyard.Value:
+			elt = Literal(item.(shuntingyard.Value))
+		case shuntingyard.Elt:
+			elt = item.(shuntingyard.Elt)
+		default:
+			t.Fatalf("Unexpected item %#v", item)
+		}
+		err = e.Append(elt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, e.Finalize()
+}
+
+/* build and execute the expression, and check its output types unless
+ * typ is empty. return the values, nil on error.
+ */
+func build_execute(t *testing.T, r *shuntingyard.Registry, ctx context.Context, typ string, items ...interface{})(*shuntingyard.Expr, []shuntingyard.Value) {
+	var e *shuntingyard.Expr
+	var v []shuntingyard.Value
+	var err error
+
+	e, err = build(t, r, items...)
+	if err != nil {
+		t.Errorf("Unexpected error for %v: %s", items, err.Error())
+		return nil, nil
+	}
+	if typ != "" && shuntingyard.Type_list(e.Output_types()) != typ {
+		t.Errorf("Expect type %s for %q, got %s", typ, e.String(), shuntingyard.Type_list(e.Output_types()))
+	}
+	v, err = e.Execute(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+		return e, nil
+	}
+	return e, v
+}
+
+func Test_stdlib(t *testing.T) {
+	var r *shuntingyard.Registry
+	var e *shuntingyard.Expr
+	var err error
+	var v []shuntingyard.Value
+	var ctx context.Context
+	var now time.Time
+	var xp *shuntingyard.Explanation
+	var c struct {
+		items []interface{}
+		expect string
+	}
+
+	ctx = shuntingyard.With_checked(context.Background())
+	r = New_registry()
+	now = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	for _, c = range []struct {
+		items []interface{}
+		expect string
+	}{
+		{[]interface{}{Int64(1), "+", Int64(2), "*", Int64(3)}, "7:int64"},
+		{[]interface{}{"(", Int64(1), "+", Int64(2), ")", "*", Int64(3)}, "9:int64"},
+		{[]interface{}{Float64(1.5), "*", Float64(2), "-", "neg", Float64(1)}, "4:float64"},
+		{[]interface{}{Int64(7), "/", Int64(2), "+", Int64(7), "%", Int64(4)}, "6:int64"},
+		{[]interface{}{String("ab"), "+", String("cd")}, "abcd:string"},
+		{[]interface{}{"len", String("abc"), ">=", Int64(3)}, "true:bool"},
+		{[]interface{}{"len", Bytes([]byte("ab"))}, "2:int64"},
+		{[]interface{}{"upper", String("ab"), "==", String("AB"), "and", "lower", String("CD"), "!=", String("cd")}, "false:bool"},
+		{[]interface{}{String("abc"), "contains", String("b"), "and", String("abc"), "starts_with", String("a"),
+		               "and", String("abc"), "ends_with", String("c")}, "true:bool"},
+		{[]interface{}{"not", Bool(true), "or", Int64(2), "<", Int64(3), "and", Float64(2), ">", Float64(3)}, "false:bool"},
+		{[]interface{}{"not", Bool(false), "and", Bool(true), "==", Bool(true)}, "true:bool"},
+		{[]interface{}{Time(now), "+", Duration(time.Hour), "-", Time(now)}, "1h0m0s:time.Duration"},
+		{[]interface{}{Time(now), "-", Duration(time.Hour), "<", Time(now)}, "true:bool"},
+		{[]interface{}{Duration(time.Second), "+", "neg", Duration(time.Minute), "<=", Duration(0)}, "true:bool"},
+		{[]interface{}{Bytes([]byte("a")), "==", Bytes([]byte("a"))}, "true:bool"},
+		{[]interface{}{String("a"), "<", String("b")}, "true:bool"},
+		{[]interface{}{"nil"}, "nil:nil"},
+	} {
+		e, v = build_execute(t, r, ctx, "", c.items...)
+		if v != nil && v[0].Descr() + ":" + v[0].Type().Name() != c.expect {
+			t.Errorf("Expect %s for %q, got %s:%s", c.expect, e.String(), v[0].Descr(), v[0].Type().Name())
+		}
+	}
+
+	/* errors */
+	_, err = build(t, r, Int64(1), "+", String("a"))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e, _ = build(t, r, Int64(1), "/", Int64(0))
+	_, err = e.Execute(ctx, nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e, _ = build(t, r, Int64(1), "%", Int64(0))
+	_, err = e.Execute(ctx, nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+
+	/* literals */
+	if Literal(String("a")).String() != "\"a\"" || Literal(Bytes([]byte("a"))).String() != "\"a\"" ||
+	   Literal(Float64(1.5)).String() != "1.5" {
+		t.Errorf("Unexpected literal symbols")
+	}
+
+	/* explain uses decisive inputs of logical operators */
+	e, _ = build(t, r, Bool(true), "or", Int64(1), "<", Int64(0))
+	xp, err = e.Explain(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if xp.Root[0].Children[0].Decisive != true || xp.Root[0].Children[1].Decisive != false {
+		t.Errorf("Expect only left operand of \"or\" decisive:\n%s", xp.String())
+	}
+
+	if r.Add(And) == nil {
+		t.Errorf("Expect error, got no error")
+	}
+}
+
+/* variable x of type int64|nil, its value is stored in the context */
+type x_key struct {}
+
+type x_elt struct {}
+
+func (x *x_elt)Precedence()(int) { return 0 }
+func (x *x_elt)Associativity()(int) { return 0 }
+func (x *x_elt)Kind()(int) { return shuntingyard.Kind_value }
+func (x *x_elt)String()(string) { return "x" }
+func (x *x_elt)Input_types()([][]shuntingyard.Type) { return nil }
+func (x *x_elt)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{Type_int64, Type_nil}}
+}
+func (x *x_elt)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	return []shuntingyard.Value{ctx.Value(x_key{}).(shuntingyard.Value)}, nil
+}
+func (x *x_elt)Variable()(interface{}) { return x_key{} }
+
+var x_var *x_elt = &x_elt{}
+
+func Test_null(t *testing.T) {
+	var r *shuntingyard.Registry
+	var e *shuntingyard.Expr
+	var err error
+	var v []shuntingyard.Value
+	var with_nil context.Context
+	var with_2 context.Context
+	var semantic int
+	var xp *shuntingyard.Explanation
+	var c struct {
+		items []interface{}
+		ctx context.Context
+		expect string
+	}
+
+	with_nil = context.WithValue(context.Background(), x_key{}, Nil)
+	with_2 = context.WithValue(context.Background(), x_key{}, Int64(2))
+
+	/* strict */
+	r = New_registry_null(Null_strict)
+	_, err = build(t, r, x_var, "+", Int64(1))
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e, err = build(t, r, "is_nil", x_var)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	/* error */
+	r = New_registry_null(Null_error)
+	e, err = build(t, r, x_var, "+", Int64(1), "*", Int64(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(e.Warnings()) != 1 || e.Warnings()[0] != "Nullable value int64|nil reaches non-null \"+\" at position 4" {
+		t.Errorf("Unexpected warnings %q", e.Warnings())
+	}
+	if shuntingyard.Type_list(e.Output_types()) != "int64" {
+		t.Errorf("Expect \"int64\", got %q", shuntingyard.Type_list(e.Output_types()))
+	}
+	_, err = e.Execute(with_nil, nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	v, err = e.Execute(with_2, nil)
+	if err != nil || v[0].Descr() != "4" {
+		t.Errorf("Expect 4, got %v %v", v, err)
+	}
+	e, err = build(t, r, x_var, "==", "nil")
+	if err != nil || len(e.Warnings()) != 0 {
+		t.Errorf("Expect no warning for equality")
+	}
+
+	/* propagate */
+	r = New_registry_null(Null_propagate)
+	e, err = build(t, r, x_var, "+", Int64(1), "*", Int64(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if shuntingyard.Type_list(e.Output_types()) != "int64|nil" {
+		t.Errorf("Expect \"int64|nil\", got %q", shuntingyard.Type_list(e.Output_types()))
+	}
+	e, _ = build(t, r, Int64(1), "+", Int64(2))
+	if shuntingyard.Type_list(e.Output_types()) != "int64" {
+		t.Errorf("Expect \"int64\", got %q", shuntingyard.Type_list(e.Output_types()))
+	}
+
+	for _, c = range []struct {
+		items []interface{}
+		ctx context.Context
+		expect string
+	}{
+		{[]interface{}{x_var, "+", Int64(1)}, with_nil, "nil"},
+		{[]interface{}{x_var, "+", Int64(1)}, with_2, "3"},
+		{[]interface{}{x_var, "==", "nil"}, with_nil, "true"},
+		{[]interface{}{x_var, "!=", "nil"}, with_nil, "false"},
+		{[]interface{}{x_var, "==", "nil"}, with_2, "false"},
+		{[]interface{}{x_var, ">", Int64(1), "or", Bool(true)}, with_nil, "nil"},
+	} {
+		e, v = build_execute(t, r, shuntingyard.With_checked(c.ctx), "", c.items...)
+		if v != nil && v[0].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[0].Descr())
+		}
+	}
+
+	/* sql unknown */
+	r = New_registry_null(Null_unknown)
+	for _, c = range []struct {
+		items []interface{}
+		ctx context.Context
+		expect string
+	}{
+		{[]interface{}{x_var, ">", Int64(1), "or", Bool(true)}, with_nil, "true"},
+		{[]interface{}{x_var, ">", Int64(1), "or", Bool(false)}, with_nil, "nil"},
+		{[]interface{}{x_var, ">", Int64(1), "and", Bool(false)}, with_nil, "false"},
+		{[]interface{}{x_var, ">", Int64(1), "and", Bool(true)}, with_nil, "nil"},
+		{[]interface{}{x_var, ">", Int64(1), "and", Bool(true)}, with_2, "true"},
+		{[]interface{}{"not", x_var, ">", Int64(1)}, with_nil, "nil"},
+		{[]interface{}{x_var, "-", Int64(1)}, with_nil, "nil"},
+	} {
+		e, v = build_execute(t, r, shuntingyard.With_checked(c.ctx), "", c.items...)
+		if v != nil && v[0].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[0].Descr())
+		}
+	}
+
+	/* nil == nil is the comparison of two nil, whatever the semantic */
+	for _, semantic = range []int{Null_strict, Null_error, Null_propagate, Null_unknown} {
+		r = New_registry_null(semantic)
+		for _, c = range []struct {
+			items []interface{}
+			ctx context.Context
+			expect string
+		}{
+			{[]interface{}{"nil", "==", "nil"}, nil, "true"},
+			{[]interface{}{"nil", "!=", "nil"}, nil, "false"},
+		} {
+			e, v = build_execute(t, r, shuntingyard.With_checked(context.Background()), "", c.items...)
+			if v != nil && v[0].Descr() != c.expect {
+				t.Errorf("Expect %s for %q with semantic %d, got %s", c.expect, e.String(), semantic, v[0].Descr())
+			}
+		}
+	}
+
+	/* the absorbing operand determines the result */
+	e, _ = build(t, r, x_var, ">", Int64(1), "and", Bool(false))
+	xp, err = e.Explain(with_nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if xp.Root[0].Children[0].Decisive || !xp.Root[0].Children[1].Decisive {
+		t.Errorf("Expect only right operand decisive:\n%s", xp.String())
+	}
+}
+
+func Test_narrowing(t *testing.T) {
+	var r *shuntingyard.Registry
+	var e *shuntingyard.Expr
+	var err error
+	var v []shuntingyard.Value
+	var with_nil context.Context
+	var with_2 context.Context
+	var xp *shuntingyard.Explanation
+	var items []interface{}
+	var c struct {
+		items []interface{}
+		ctx context.Context
+		expect string
+	}
+
+	with_nil = shuntingyard.With_checked(context.WithValue(context.Background(), x_key{}, Nil))
+	with_2 = shuntingyard.With_checked(context.WithValue(context.Background(), x_key{}, Int64(2)))
+
+	r = New_registry_null(Null_strict)
+	for _, c = range []struct {
+		items []interface{}
+		ctx context.Context
+		expect string
+	}{
+		{[]interface{}{x_var, "!=", "nil", "and", x_var, ">", Int64(1)}, with_nil, "false"},
+		{[]interface{}{x_var, "!=", "nil", "and", x_var, ">", Int64(1)}, with_2, "true"},
+		{[]interface{}{"nil", "!=", x_var, "and", x_var, ">", Int64(1)}, with_2, "true"},
+		{[]interface{}{x_var, "==", "nil", "or", x_var, ">", Int64(3)}, with_nil, "true"},
+		{[]interface{}{x_var, "==", "nil", "or", x_var, ">", Int64(3)}, with_2, "false"},
+		{[]interface{}{"not", "is_nil", x_var, "and", x_var, "*", Int64(2), "==", Int64(4)}, with_2, "true"},
+		{[]interface{}{"not", "is_nil", x_var, "and", x_var, "*", Int64(2), "==", Int64(4)}, with_nil, "false"},
+		{[]interface{}{x_var, "!=", "nil", "and", "(", x_var, ">", Int64(0), "and", x_var, "<", Int64(3), ")"}, with_2, "true"},
+		{[]interface{}{"if", "(", x_var, "!=", "nil", ")", "(", x_var, ")", "(", Int64(0), ")"}, with_nil, "0"},
+		{[]interface{}{"if", "(", x_var, "!=", "nil", ")", "(", x_var, ")", "(", Int64(0), ")", "+", Int64(1)}, with_2, "3"},
+		{[]interface{}{"if", "(", x_var, "==", "nil", ")", "(", Int64(0), ")", "(", x_var, "-", Int64(1), ")"}, with_2, "1"},
+	} {
+		e, v = build_execute(t, r, c.ctx, "", c.items...)
+		if v != nil && v[0].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[0].Descr())
+		}
+	}
+
+	/* the narrowing applies only to the operand evaluated after the guard */
+	for _, items = range [][]interface{}{
+		{x_var, ">", Int64(1), "and", x_var, "!=", "nil"},
+		{x_var, "!=", "nil", "or", x_var, ">", Int64(1)},
+		{x_var, "==", "nil", "and", x_var, ">", Int64(1)},
+		{"if", "(", x_var, "!=", "nil", ")", "(", Int64(0), ")", "(", x_var, ")"},
+	} {
+		_, err = build(t, r, items...)
+		if err == nil {
+			t.Errorf("Expect error for %v, got no error", items)
+		}
+	}
+
+	/* the narrowed operand doesn't reach the operators with nil */
+	r = New_registry_null(Null_error)
+	e, err = build(t, r, x_var, "!=", "nil", "and", x_var, "+", Int64(1), ">", Int64(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(e.Warnings()) != 0 {
+		t.Errorf("Unexpected warnings %q", e.Warnings())
+	}
+	xp, err = e.Explain(with_nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if xp.Result[0].Descr() != "false" || xp.Root[0].Children[1].Symbol != "<skipped>" {
+		t.Errorf("Expect skipped right operand:\n%s", xp.String())
+	}
+}
+
+/* build lambda with int64 parameters, the parameters are referenced by name */
+func build_lambda(t *testing.T, r *shuntingyard.Registry, params []string, items ...interface{})(*shuntingyard.Lambda) {
+	var l *shuntingyard.Lambda
+	var vars map[string]shuntingyard.Elt
+	var name string
+	var item interface{}
+	var elt shuntingyard.Elt
+	var ok bool
+	var err error
+
+	l = shuntingyard.New_lambda()
+	vars = make(map[string]shuntingyard.Elt)
+	for _, name = range params {
+		vars[name] = l.Param(name, Type_int64)
+	}
+	for _, item = range items {
+		switch item.(type) {
+		case string:
+			elt, ok = vars[item.(string)]
+			if !ok {
+				elt, ok = r.Get(item.(string))
+			}
+			if !ok {
+				t.Fatalf("Unknown symbol %q", item)
+			}
+		case shuntingyard.Value:
+			elt = Literal(item.(shuntingyard.Value))
+		default:
+			t.Fatalf("Unexpected item %#v", item)
+		}
+		err = l.Append(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	err = l.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	return l
+}
+
+func Test_lists(t *testing.T) {
+	var r *shuntingyard.Registry
+	var e *shuntingyard.Expr
+	var err error
+	var v []shuntingyard.Value
+	var ctx context.Context
+	var items shuntingyard.Value
+	var names shuntingyard.Value
+	var c struct {
+		items []interface{}
+		expect string
+		typ string
+	}
+
+	ctx = shuntingyard.With_checked(context.Background())
+	r = New_registry()
+	items = shuntingyard.New_list_value(Type_int64, []shuntingyard.Value{Int64(1), Int64(5), Int64(12)})
+	names = shuntingyard.New_list_value(Type_string, []shuntingyard.Value{String("a")})
+
+	for _, c = range []struct {
+		items []interface{}
+		expect string
+		typ string
+	}{
+		{[]interface{}{"any", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(10)), ")"},
+		 "true", "bool"},
+		{[]interface{}{"any", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(20)), ")"},
+		 "false", "bool"},
+		{[]interface{}{"all", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(0)), ")"},
+		 "true", "bool"},
+		{[]interface{}{"all", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(1)), ")"},
+		 "false", "bool"},
+		{[]interface{}{"filter", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(3)), ")"},
+		 "[5, 12]", "list<int64>"},
+		{[]interface{}{"map", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", "*", Int64(2)), ")"},
+		 "[2, 10, 24]", "list<int64>"},
+		{[]interface{}{"map", "(", items, ")", "(", build_lambda(t, r, []string{"x"}, "x", "%", Int64(2), "==", Int64(0)), ")"},
+		 "[false, false, true]", "list<bool>"},
+		{[]interface{}{"reduce", "(", items, ")", "(", Int64(100), ")", "(",
+		               build_lambda(t, r, []string{"acc", "x"}, "acc", "-", "x"), ")"},
+		 "82", "int64"},
+	} {
+		e, v = build_execute(t, r, ctx, c.typ, c.items...)
+		if v != nil && v[0].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[0].Descr())
+		}
+	}
+
+	/* the lambda parameter must match the items */
+	_, err = build(t, r, "any", "(", names, ")", "(", build_lambda(t, r, []string{"x"}, "x", ">", Int64(10)), ")")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	_, err = build(t, r, "reduce", "(", items, ")", "(", String("a"), ")", "(",
+	               build_lambda(t, r, []string{"acc", "x"}, "acc", "-", "x"), ")")
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+}
+
+func Test_tuples(t *testing.T) {
+	var r *shuntingyard.Registry
+	var e *shuntingyard.Expr
+	var err error
+	var v []shuntingyard.Value
+	var ctx context.Context
+	var items []interface{}
+	var c struct {
+		items []interface{}
+		expect string
+		typ string
+	}
+
+	ctx = shuntingyard.With_checked(context.Background())
+	r = New_registry()
+
+	for _, c = range []struct {
+		items []interface{}
+		expect string
+		typ string
+	}{
+		{[]interface{}{shuntingyard.Pack(2), "(", "divmod", "(", Int64(7), ")", "(", Int64(2), ")", ")"},
+		 "(3, 1)", "tuple<int64, int64>"},
+		{[]interface{}{shuntingyard.Pack(2, "q", "r"), "(", "divmod", "(", Int64(7), ")", "(", Int64(2), ")", ")"},
+		 "(q: 3, r: 1)", "tuple<q: int64, r: int64>"},
+		{[]interface{}{"(", shuntingyard.Pack(2), "(", "divmod", "(", Int64(7), ")", "(", Int64(2), ")", ")", ")",
+		               shuntingyard.Project(1), "+", Int64(10)},
+		 "11", "int64"},
+		{[]interface{}{"(", shuntingyard.Pack(2, "q", "r"), "(", "divmod", "(", Int64(17), ")", "(", Int64(5), ")", ")", ")",
+		               shuntingyard.Project_name("q"), "*", Int64(2)},
+		 "6", "int64"},
+		{[]interface{}{"(", shuntingyard.Pack(2), "(", String("a"), ")", "(", Int64(1), ")", ")",
+		               shuntingyard.Project(0), "+", String("b")},
+		 "ab", "string"},
+		{[]interface{}{shuntingyard.Unpack(2), "(", shuntingyard.Pack(2), "(", "divmod", "(", Int64(7), ")", "(", Int64(2), ")", ")", ")",
+		               "-", Int64(1)},
+		 "0", "int64, int64"},
+	} {
+		e, v = build_execute(t, r, ctx, c.typ, c.items...)
+		if v != nil && v[len(v) - 1].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[len(v) - 1].Descr())
+		}
+	}
+
+	/* the projections are checked against the fields */
+	for _, items = range [][]interface{}{
+		{"(", shuntingyard.Pack(2), "(", Int64(1), ")", "(", Int64(2), ")", ")", shuntingyard.Project(2)},
+		{"(", shuntingyard.Pack(2), "(", Int64(1), ")", "(", Int64(2), ")", ")", shuntingyard.Project_name("q")},
+		{"(", shuntingyard.Pack(2), "(", String("a"), ")", "(", Int64(2), ")", ")", shuntingyard.Project(0), "+", Int64(1)},
+		{Int64(1), shuntingyard.Project(0)},
+		{shuntingyard.Unpack(3), "(", shuntingyard.Pack(2), "(", Int64(1), ")", "(", Int64(2), ")", ")"},
+	} {
+		_, err = build(t, r, items...)
+		if err == nil {
+			t.Errorf("Expect error for %v, got no error", items)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package stdlib provides ready-made types, values and operators for
+// the shuntingyard expressions.
+//
+// The types are the types of the generic API, so the values built by
+// shuntingyard.Value_of and by the reflection binder are compatible
+// with the standard operators.
+package stdlib
+
+import "context"
+import "fmt"
+import "time"
+
+import "github.com/thierry-f-78/go-shuntingyard"
+
+var Type_bool shuntingyard.Type = shuntingyard.Type_of[bool]()
+var Type_int64 shuntingyard.Type = shuntingyard.Type_of[int64]()
+var Type_float64 shuntingyard.Type = shuntingyard.Type_of[float64]()
+var Type_string shuntingyard.Type = shuntingyard.Type_of[string]()
+var Type_bytes shuntingyard.Type = shuntingyard.Type_of[[]byte]()
+var Type_time shuntingyard.Type = shuntingyard.Type_of[time.Time]()
+var Type_duration shuntingyard.Type = shuntingyard.Type_of[time.Duration]()
+var Type_nil shuntingyard.Type = &nil_type{}
+
+/* type of the nil value */
+type nil_type struct {}
+
+func (t *nil_type)Name()(string) {
+	return "nil"
+}
+
+func (t *nil_type)Is_null()(bool) {
+	return true
+}
+
+// Type accepting values of all the types
+var Type_any shuntingyard.Type = &any_type{}
+
+type any_type struct {}
+
+func (t *any_type)Name()(string) {
+	return "any"
+}
+
+func (t *any_type)Accepts(r shuntingyard.Type)(bool) {
+	return true
+}
+
+/* the nil value */
+type nil_value struct {}
+
+func (v *nil_value)Descr()(string) {
+	return "nil"
+}
+
+func (v *nil_value)Type()(shuntingyard.Type) {
+	return Type_nil
+}
+
+// The nil value
+var Nil shuntingyard.Value = &nil_value{}
+
+func Bool(v bool)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func Int64(v int64)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func Float64(v float64)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func String(v string)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func Bytes(v []byte)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func Time(v time.Time)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+func Duration(v time.Duration)(shuntingyard.Value) {
+	return shuntingyard.Value_of(v)
+}
+
+/* value element returning a constant */
+type literal struct {
+	symbol string
+	value shuntingyard.Value
+}
+
+func (l *literal)Precedence()(int) {
+	return 0
+}
+func (l *literal)Associativity()(int) {
+	return 0
+}
+func (l *literal)Kind()(int) {
+	return shuntingyard.Kind_value
+}
+func (l *literal)String()(string) {
+	return l.symbol
+}
+func (l *literal)Input_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{}
+}
+func (l *literal)Output_types()([][]shuntingyard.Type) {
+	return [][]shuntingyard.Type{[]shuntingyard.Type{l.value.Type()}}
+}
+func (l *literal)Execute(ctx context.Context, in []shuntingyard.Value)([]shuntingyard.Value, error) {
+	return []shuntingyard.Value{l.value}, nil
+}
+
+// Return value element returning v. The element is displayed using
+// the Go syntax of the value, like 1.5, "abc" or true.
+func Literal(v shuntingyard.Value)(shuntingyard.Elt) {
+	var symbol string
+	var b []byte
+
+	switch v.Type() {
+	case Type_string:
+		symbol = fmt.Sprintf("%q", v.Descr())
+	case Type_bytes:
+		b, _ = shuntingyard.Get[[]byte](v)
+		symbol = fmt.Sprintf("%q", b)
+	default:
+		symbol = v.Descr()
+	}
+	return &literal{
+		symbol: symbol,
+		value: v,
+	}
+}
@@ -0,0 +1,166 @@
+package shuntingyard
+
+import "fmt"
+
+/* marker of the groups in the stack of juxtaposed operands */
+const syntax_group = -1
+
+/* operands which can still be juxtaposed for the operator owner, or
+ * syntax_group with a nil owner for a group.
+ */
+type syntax_slot struct {
+	open int
+	owner *elt_cache
+}
+
+/* return true if the pending operator top is popped by the operator ec */
+func pops(top *elt_cache, ec *elt_cache)(bool) {
+	return top.precedence > ec.precedence ||
+	       (top.precedence == ec.precedence && top.associativity == Associativity_left)
+}
+
+/* return the number of slots kept when the operators of the innermost
+ * group are closed: none of them for a group close, the ones which are
+ * not popped by the operator ec.
+ */
+func (e *Expr)close_slots(ec *elt_cache)(int) {
+	var top int
+
+	for top = len(e.slots) - 1; top >= 0; top-- {
+		if e.slots[top].open == syntax_group {
+			break
+		}
+		if ec.kind == Kind_operator && !pops(e.slots[top].owner, ec) {
+			break
+		}
+	}
+	return top + 1
+}
+
+/* check that the pending operators popped by ec have all their operands.
+ * an operand may push many values, so they are counted on the rpn. the
+ * inputs of the expression can provide the missing operands.
+ */
+func (e *Expr)check_popped(ec *elt_cache)(error) {
+	var rpn []*elt_cache
+	var shape *rpn_shape
+	var top *elt_cache
+	var need int
+	var i int
+
+	if len(e.input_types) > 0 {
+		return nil
+	}
+	rpn = e.rpn
+	for i = len(e.precedence_stack) - 1; i >= 0; i-- {
+		top = e.precedence_stack[i]
+		if top.kind == Kind_group_open {
+			break
+		}
+		if ec.kind == Kind_operator && !pops(top, ec) {
+			break
+		}
+		/* the left operand of an infix operator is before its base */
+		need = len(top.input_types)
+		if top.infix {
+			need--
+		}
+		shape = new_rpn_shape(rpn, 0)
+		if shape.depth[len(rpn)] - shape.depth[top.base] < need {
+			return fmt.Errorf("Syntax error, missing operand of %q before %q",
+			                  top.elt.String(), ec.elt.String())
+		}
+		rpn = append(rpn[:len(rpn):len(rpn)], top)
+	}
+	return nil
+}
+
+/* check that elt can follow the elements appended before, and update
+ * the state. the expression expects an operand at start, after an open
+ * group and after a prefix or infix operator, otherwise it expects an
+ * operator. a prefix operator with n inputs accepts n-1 operands
+ * juxtaposed after its first one: "if (c) (a) (b)". the right associative
+ * operators with one input are prefix, the left associative ones are
+ * postfix, the others are accepted at both places. an operator closes
+ * the juxtaposition of the operators it pops. the state is not changed
+ * on error.
+ */
+func (e *Expr)check_syntax(ec *elt_cache)(error) {
+	var n int
+	var top int
+	var keep int
+	var err error
+
+	switch ec.kind {
+
+	case Kind_value, Kind_group_open:
+		if e.operand {
+			top = len(e.slots) - 1
+			if top < 0 || e.slots[top].open <= 0 {
+				return fmt.Errorf("Syntax error, unexpected operand %q after operand %q",
+				                  ec.elt.String(), e.name_elements[len(e.name_elements) - 1])
+			}
+			e.slots[top].open--
+		}
+		if ec.kind == Kind_group_open {
+			e.slots = append(e.slots, syntax_slot{open: syntax_group})
+			e.operand = false
+		} else {
+			e.operand = true
+		}
+
+	case Kind_group_close:
+		if !e.operand {
+			return fmt.Errorf("Syntax error, missing operand before %q", ec.elt.String())
+		}
+		err = e.check_popped(ec)
+		if err != nil {
+			return err
+		}
+		top = e.close_slots(ec) - 1
+		if top < 0 {
+			return fmt.Errorf("Expression error, encounter %q, but this symbol is not associated", ec.elt.String())
+		}
+		e.slots = e.slots[:top]
+
+	case Kind_operator:
+		n = len(ec.input_types)
+		if n == 0 {
+			return nil
+		}
+		/* the popped operators don't accept operands anymore */
+		err = e.check_popped(ec)
+		if err != nil {
+			return err
+		}
+		keep = e.close_slots(ec)
+		/* the first operator may apply to the inputs of the expression */
+		if e.operand || (len(e.name_elements) == 0 && len(e.input_types) > 0 &&
+		                 ec.associativity == Associativity_left) {
+			/* postfix or infix operator */
+			if n == 1 && ec.associativity == Associativity_right {
+				return fmt.Errorf("Syntax error, prefix operator %q after operand %q",
+				                  ec.elt.String(), e.name_elements[len(e.name_elements) - 1])
+			}
+			e.slots = e.slots[:keep]
+			e.operand = n == 1
+			ec.infix = true
+			if n > 2 {
+				e.slots = append(e.slots, syntax_slot{open: n - 2, owner: ec})
+			}
+			return nil
+		}
+		/* prefix operator */
+		if ec.associativity == Associativity_left {
+			return fmt.Errorf("Syntax error, operator %q needs a left operand", ec.elt.String())
+		}
+		e.slots = e.slots[:keep]
+		if n > 1 {
+			e.slots = append(e.slots, syntax_slot{open: n - 1, owner: ec})
+		}
+
+	default:
+		return fmt.Errorf("Unexpected kind value %s for %s", kind_str(ec.kind), ec.elt.String())
+	}
+	return nil
+}
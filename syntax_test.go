@@ -0,0 +1,109 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+func Test_syntax(t *testing.T) {
+	var add Elt
+	var mul Elt
+	var neg Elt
+	var one Elt
+	var two Elt
+	var e *Expr
+	var v []Value
+	var elt Elt
+	var i int
+	var err error
+	var c struct {
+		elts []Elt
+		bad int
+		expect string
+	}
+
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	mul = Binary("*", 2, Associativity_left, func(a int64, b int64)(int64, error) { return a * b, nil })
+	neg = Unary("neg", 3, Associativity_right, func(a int64)(int64, error) { return -a, nil })
+	one = Constant("1", int64(1))
+	two = Constant("2", int64(2))
+
+	for _, c = range []struct {
+		elts []Elt
+		bad int
+		expect string
+	}{
+		{[]Elt{one, add, neg, two, mul, two}, -1, ""},
+		{[]Elt{op_open, one, op_close, add, op_open, neg, two, op_close}, -1, ""},
+		{[]Elt{Pack(3), op_open, one, op_close, op_open, two, op_close, op_open, one, op_close}, -1, ""},
+		{[]Elt{op_open, Pack(2), one, two, op_close, Project(1), add, one}, -1, ""},
+		{[]Elt{one, two}, 1, `Syntax error, unexpected operand "2" after operand "1"`},
+		{[]Elt{one, add, mul, two}, 2, `Syntax error, operator "*" needs a left operand`},
+		{[]Elt{mul, two}, 0, `Syntax error, operator "*" needs a left operand`},
+		{[]Elt{one, neg}, 1, `Syntax error, prefix operator "neg" after operand "1"`},
+		{[]Elt{one, add, op_close}, 2, `Syntax error, missing operand before ")"`},
+		{[]Elt{op_open, one, op_close, op_open, two, op_close}, 3, `Syntax error, unexpected operand "(" after operand ")"`},
+		{[]Elt{Pack(2), one, two, one}, 3, `Syntax error, unexpected operand "1" after operand "2"`},
+		{[]Elt{op_open, Pack(2), one, op_close, two}, 3, `Syntax error, missing operand of "tuple" before ")"`},
+		{[]Elt{Pack(2), one, add, two, one}, 2, `Syntax error, missing operand of "tuple" before "+"`},
+		{[]Elt{Pack(2), neg, one, two}, 1, `Syntax error, missing operand of "tuple" before "neg"`},
+		{[]Elt{op_open, Pack(2), op_open, Unpack(2), Pack(2), one, two, op_close, op_close, Project(0), add, one}, -1, ""},
+	} {
+		e = New(nil)
+		for i, elt = range c.elts {
+			err = e.Append(elt)
+			if i == c.bad {
+				if err == nil || err.Error() != c.expect {
+					t.Errorf("Expect error %q at %q, got %v", c.expect, elt.String(), err)
+				}
+				break
+			}
+			if err != nil {
+				t.Errorf("Unexpected error at %q: %s", elt.String(), err.Error())
+				break
+			}
+		}
+		if c.bad < 0 {
+			err = e.Finalize()
+			if err != nil {
+				t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+			}
+		}
+	}
+
+	/* the expression can be continued after the error */
+	e = New(nil)
+	e.Append(one)
+	err = e.Append(two)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	e.Append(add)
+	e.Append(two)
+	err = e.Finalize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	v, err = e.Execute(context.Background(), nil)
+	if err != nil || v[0].Descr() != "3" {
+		t.Errorf("Expect 3, got %v, %v", v, err)
+	}
+
+	/* a rejected first element leaves the expression empty */
+	e = New(nil)
+	err = e.Append(mul)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	/* the last operator needs its operand */
+	e = New(nil)
+	e.Append(one)
+	e.Append(add)
+	err = e.Finalize()
+	if err == nil || err.Error() != `Syntax error, missing operand after "+"` {
+		t.Errorf("Expect missing operand error, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package shuntingyard
+
+import "fmt"
+import "strings"
+import "time"
+
+// This interface receive execution events. It is set using With_tracer.
+// The slices of values are only valid during the call, the tracer must
+// copy them if it keep it.
+type Tracer interface {
+	// called when the expression e starts its execution
+	Enter(e *Expr, in []Value)
+	// called when the expression e ends its execution
+	Leave(e *Expr, out []Value, err error, d time.Duration)
+	// called before executing the element at position pos of the expression e
+	Before(e *Expr, pos int, elt Elt, in []Value)
+	// called after executing the element at position pos of the expression e
+	After(e *Expr, pos int, elt Elt, in []Value, out []Value, err error, d time.Duration)
+}
+
+const (
+	Trace_enter = iota
+	Trace_leave
+	Trace_elt
+)
+
+// One event recorded by the Recorder
+type Trace_step struct {
+	// kind of event. use Trace_*
+	Event int
+	// nesting level of the expression, 0 for the executed expression
+	Depth int
+	// expression which generate the event
+	Expr *Expr
+	// position of the element in the expression (Trace_elt only)
+	Position int
+	// element executed (Trace_elt only)
+	Elt Elt
+	Inputs []Value
+	Outputs []Value
+	Err error
+	Duration time.Duration
+}
+
+// Built-in tracer which record all the execution steps. The recorder
+// is not safe for concurrent use.
+type Recorder struct {
+	Steps []*Trace_step
+	// display durations in the trace
+	Show_duration bool
+	depth int
+}
+
+func New_recorder()(*Recorder) {
+	return &Recorder{}
+}
+
+func copy_values(vs []Value)([]Value) {
+	var out []Value
+
+	out = make([]Value, len(vs))
+	copy(out, vs)
+	return out
+}
+
+func (r *Recorder)Enter(e *Expr, in []Value) {
+	r.Steps = append(r.Steps, &Trace_step{
+		Event: Trace_enter,
+		Depth: r.depth,
+		Expr: e,
+		Inputs: copy_values(in),
+	})
+	r.depth++
+}
+
+func (r *Recorder)Leave(e *Expr, out []Value, err error, d time.Duration) {
+	r.depth--
+	r.Steps = append(r.Steps, &Trace_step{
+		Event: Trace_leave,
+		Depth: r.depth,
+		Expr: e,
+		Outputs: copy_values(out),
+		Err: err,
+		Duration: d,
+	})
+}
+
+func (r *Recorder)Before(e *Expr, pos int, elt Elt, in []Value) {
+}
+
+func (r *Recorder)After(e *Expr, pos int, elt Elt, in []Value, out []Value, err error, d time.Duration) {
+	r.Steps = append(r.Steps, &Trace_step{
+		Event: Trace_elt,
+		Depth: r.depth,
+		Expr: e,
+		Position: pos,
+		Elt: elt,
+		Inputs: copy_values(in),
+		Outputs: copy_values(out),
+		Err: err,
+		Duration: d,
+	})
+}
+
+// Forget all recorded steps
+func (r *Recorder)Reset() {
+	r.Steps = nil
+	r.depth = 0
+}
+
+/* describe value with its type */
+func value_desc(v Value)(string) {
+	if v == nil {
+		return "<nil>"
+	}
+	return v.Descr() + ":" + Type_string(v.Type())
+}
+
+/* describe list of values */
+func values_desc(vs []Value)(string) {
+	var out []string
+	var v Value
+
+	for _, v = range vs {
+		out = append(out, value_desc(v))
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+// Describe one step
+func (s *Trace_step)String()(string) {
+	var out string
+
+	switch s.Event {
+	case Trace_enter:
+		return fmt.Sprintf("enter [%s] with %s", s.Expr.String(), values_desc(s.Inputs))
+	case Trace_leave:
+		out = fmt.Sprintf("leave [%s]", s.Expr.String())
+	default:
+		out = fmt.Sprintf("#%d %s %s", s.Position, s.Elt.String(), values_desc(s.Inputs))
+	}
+	if s.Err != nil {
+		out += " -> error: " + s.Err.Error()
+	} else {
+		out += " -> " + values_desc(s.Outputs)
+	}
+	return out
+}
+
+// Return readable step by step trace
+func (r *Recorder)String()(string) {
+	var b strings.Builder
+	var s *Trace_step
+
+	for _, s = range r.Steps {
+		b.WriteString(strings.Repeat("|   ", s.Depth))
+		b.WriteString(s.String())
+		if r.Show_duration && s.Event != Trace_enter {
+			fmt.Fprintf(&b, " [%s]", s.Duration)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
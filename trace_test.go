@@ -0,0 +1,67 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+func Test_recorder(t *testing.T) {
+	var e *Expr
+	var se *Expr
+	var r *Recorder
+	var err error
+	var expect string
+
+	se = New(nil)
+	se.Append(op_23)
+	se.Append(op_add)
+	se.Append(op_24)
+	se.Finalize()
+
+	e = New(nil)
+	e.Append(se)
+	e.Append(op_mul)
+	e.Append(op_neg)
+	e.Append(op_25)
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	r = New_recorder()
+	_, err = e.Execute(With_tracer(context.Background(), r), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	expect = "enter [2.3 + 2.4 * neg 2.5] with ()\n" +
+	         "|   enter [2.3 + 2.4] with ()\n" +
+	         "|   |   #0 2.3 () -> (2.300000:float64)\n" +
+	         "|   |   #1 2.4 () -> (2.400000:float64)\n" +
+	         "|   |   #2 + (2.300000:float64, 2.400000:float64) -> (4.700000:float64)\n" +
+	         "|   leave [2.3 + 2.4] -> (4.700000:float64)\n" +
+	         "|   #0 2.3 + 2.4 () -> (4.700000:float64)\n" +
+	         "|   #1 2.5 () -> (2.500000:float64)\n" +
+	         "|   #2 neg (2.500000:float64) -> (-2.500000:float64)\n" +
+	         "|   #3 * (4.700000:float64, -2.500000:float64) -> (-11.750000:float64)\n" +
+	         "leave [2.3 + 2.4 * neg 2.5] -> (-11.750000:float64)\n"
+	if r.String() != expect {
+		t.Errorf("Expect trace:\n%s\ngot:\n%s", expect, r.String())
+	}
+
+	e = New(nil)
+	e.Append(op_23)
+	e.Append(op_add_error)
+	e.Append(op_24)
+	e.Finalize()
+	r.Reset()
+	_, err = e.Execute(With_tracer(context.Background(), r), nil)
+	if err == nil {
+		t.Errorf("Expect error, got no error")
+	}
+	expect = "enter [2.3 +e 2.4] with ()\n" +
+	         "|   #0 2.3 () -> (2.300000:float64)\n" +
+	         "|   #1 2.4 () -> (2.400000:float64)\n" +
+	         "|   #2 +e (2.300000:float64, 2.400000:float64) -> error: This is a +e error\n" +
+	         "leave [2.3 +e 2.4] -> error: This is a +e error\n"
+	if r.String() != expect {
+		t.Errorf("Expect trace:\n%s\ngot:\n%s", expect, r.String())
+	}
+}
@@ -0,0 +1,341 @@
+package shuntingyard
+
+import "context"
+import "fmt"
+import "strconv"
+import "strings"
+
+// Precedence of the tuple constructors and of the destructuring. They are
+// prefix operators: "tuple divmod (7) (2)"
+const Precedence_tuple = Precedence_macro
+
+// Create type tuple<t1, ..., tn>. The optional names designate the fields,
+// the type is displayed tuple<name1: t1, ..., namen: tn>. The names don't
+// change the compatibility of the tuples.
+func Tuple_type(fields []Type, names ...string)(*Param_type) {
+	var p *Param_type
+
+	p = New_param_type("tuple", fields...)
+	if len(names) == len(fields) {
+		p.names = names
+	}
+	return p
+}
+
+// Return the names of the arguments, nil if they are not named
+func (p *Param_type)Names()([]string) {
+	return p.names
+}
+
+/* return the index of the field name of the tuple type t, -1 if it is not found */
+func field_index(t Type, name string)(int) {
+	var p *Param_type
+	var ok bool
+	var i int
+
+	p, ok = t.(*Param_type)
+	if !ok {
+		return -1
+	}
+	for i = range p.names {
+		if p.names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Value of a tuple
+type Tuple_value struct {
+	names []string
+	Fields []Value
+}
+
+// Create tuple, the optional names designate the fields
+func New_tuple_value(fields []Value, names ...string)(*Tuple_value) {
+	var t *Tuple_value
+
+	t = &Tuple_value{
+		Fields: fields,
+	}
+	if len(names) == len(fields) {
+		t.names = names
+	}
+	return t
+}
+
+func (t *Tuple_value)Descr()(string) {
+	var out []string
+	var i int
+
+	for i = range t.Fields {
+		if t.names != nil {
+			out = append(out, t.names[i] + ": " + t.Fields[i].Descr())
+		} else {
+			out = append(out, t.Fields[i].Descr())
+		}
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+func (t *Tuple_value)Type()(Type) {
+	var fields []Type
+	var v Value
+
+	for _, v = range t.Fields {
+		fields = append(fields, v.Type())
+	}
+	return Tuple_type(fields, t.names...)
+}
+
+// Return the field name
+func (t *Tuple_value)Field(name string)(Value, bool) {
+	var i int
+
+	for i = range t.names {
+		if t.names[i] == name {
+			return t.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+/* type accepting all the tuples */
+type tuple_kind struct {}
+
+func (t *tuple_kind)Name()(string) {
+	return "tuple"
+}
+
+func (t *tuple_kind)Accepts(r Type)(bool) {
+	var p *Param_type
+	var ok bool
+
+	p, ok = r.(*Param_type)
+	return ok && p.base == "tuple"
+}
+
+/* type accepting all the types, declared by the tuple elements which
+ * infer their output types.
+ */
+type field_kind struct {}
+
+func (t *field_kind)Name()(string) {
+	return "field"
+}
+
+func (t *field_kind)Accepts(r Type)(bool) {
+	return true
+}
+
+var any_tuple []Type = []Type{&tuple_kind{}}
+var any_field []Type = []Type{&field_kind{}}
+
+/* tuple constructor */
+type pack struct {
+	names []string
+	input_types [][]Type
+	output_types [][]Type
+}
+
+// Return prefix operator which consumes n values and returns the tuple of
+// these values. The optional names designate the fields.
+func Pack(n int, names ...string)(Elt) {
+	var p *pack
+	var fields []Type
+	var v *Type_var
+	var i int
+
+	p = &pack{}
+	if len(names) == n {
+		p.names = names
+	}
+	for i = 0; i < n; i++ {
+		v = New_type_var(fmt.Sprintf("T%d", i))
+		p.input_types = append(p.input_types, []Type{v})
+		fields = append(fields, v)
+	}
+	p.output_types = [][]Type{[]Type{Tuple_type(fields, p.names...)}}
+	return p
+}
+
+func (p *pack)Precedence()(int) {
+	return Precedence_tuple
+}
+func (p *pack)Associativity()(int) {
+	return Associativity_right
+}
+func (p *pack)Kind()(int) {
+	return Kind_operator
+}
+func (p *pack)String()(string) {
+	return "tuple"
+}
+func (p *pack)Input_types()([][]Type) {
+	return p.input_types
+}
+func (p *pack)Output_types()([][]Type) {
+	return p.output_types
+}
+func (p *pack)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{New_tuple_value(append([]Value(nil), in...), p.names...)}, nil
+}
+
+/* postfix projection of a field */
+type project struct {
+	index int
+	name string
+}
+
+// Return postfix operator which returns the field index of a tuple: ".0"
+func Project(index int)(Elt) {
+	return &project{index: index}
+}
+
+// Return postfix operator which returns the field name of a tuple: ".name"
+func Project_name(name string)(Elt) {
+	return &project{index: -1, name: name}
+}
+
+func (p *project)Precedence()(int) {
+	return Precedence_member
+}
+func (p *project)Associativity()(int) {
+	return Associativity_left
+}
+func (p *project)Kind()(int) {
+	return Kind_operator
+}
+func (p *project)String()(string) {
+	if p.index < 0 {
+		return "." + p.name
+	}
+	return "." + strconv.Itoa(p.index)
+}
+func (p *project)Input_types()([][]Type) {
+	return [][]Type{any_tuple}
+}
+func (p *project)Output_types()([][]Type) {
+	return [][]Type{any_field}
+}
+
+/* the output is the field of each alternative of the tuple */
+func (p *project)Infer_output_types(in [][]Type)([][]Type, error) {
+	var out []Type
+	var t Type
+	var args []Type
+	var index int
+
+	for _, t = range in[0] {
+		index = p.index
+		if index < 0 {
+			index = field_index(t, p.name)
+			if index < 0 {
+				return nil, fmt.Errorf("%s has no field %q", t.Name(), p.name)
+			}
+		}
+		args = t.(*Param_type).args
+		if index >= len(args) {
+			return nil, fmt.Errorf("%s has no field #%d", t.Name(), index)
+		}
+		if !types_contain(out, args[index]) {
+			out = append(out, args[index])
+		}
+	}
+	return [][]Type{out}, nil
+}
+
+func (p *project)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var t *Tuple_value
+	var v Value
+	var ok bool
+
+	t, ok = in[0].(*Tuple_value)
+	if !ok {
+		return nil, fmt.Errorf("%q needs tuple, got %s", p.String(), value_desc(in[0]))
+	}
+	if p.index < 0 {
+		v, ok = t.Field(p.name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %q", t.Descr(), p.name)
+		}
+		return []Value{v}, nil
+	}
+	if p.index >= len(t.Fields) {
+		return nil, fmt.Errorf("%s has no field #%d", t.Descr(), p.index)
+	}
+	return t.Fields[p.index:p.index + 1], nil
+}
+
+/* destructuring of a tuple */
+type unpack struct {
+	output_types [][]Type
+}
+
+// Return prefix operator which consumes a tuple of n fields and returns
+// the n fields.
+func Unpack(n int)(Elt) {
+	var u *unpack
+	var i int
+
+	u = &unpack{}
+	for i = 0; i < n; i++ {
+		u.output_types = append(u.output_types, any_field)
+	}
+	return u
+}
+
+func (u *unpack)Precedence()(int) {
+	return Precedence_tuple
+}
+func (u *unpack)Associativity()(int) {
+	return Associativity_right
+}
+func (u *unpack)Kind()(int) {
+	return Kind_operator
+}
+func (u *unpack)String()(string) {
+	return "unpack"
+}
+func (u *unpack)Input_types()([][]Type) {
+	return [][]Type{any_tuple}
+}
+func (u *unpack)Output_types()([][]Type) {
+	return u.output_types
+}
+
+/* the outputs are the fields of the alternatives of the tuple */
+func (u *unpack)Infer_output_types(in [][]Type)([][]Type, error) {
+	var out [][]Type
+	var t Type
+	var args []Type
+	var i int
+
+	out = make([][]Type, len(u.output_types))
+	for _, t = range in[0] {
+		args = t.(*Param_type).args
+		if len(args) != len(u.output_types) {
+			return nil, fmt.Errorf("%s has not %d fields", t.Name(), len(u.output_types))
+		}
+		for i = range args {
+			if !types_contain(out[i], args[i]) {
+				out[i] = append(out[i], args[i])
+			}
+		}
+	}
+	return out, nil
+}
+
+func (u *unpack)Execute(ctx context.Context, in []Value)([]Value, error) {
+	var t *Tuple_value
+	var ok bool
+
+	t, ok = in[0].(*Tuple_value)
+	if !ok {
+		return nil, fmt.Errorf("%q needs tuple, got %s", u.String(), value_desc(in[0]))
+	}
+	if len(t.Fields) != len(u.output_types) {
+		return nil, fmt.Errorf("%s has not %d fields", t.Descr(), len(u.output_types))
+	}
+	return append([]Value(nil), t.Fields...), nil
+}
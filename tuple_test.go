@@ -0,0 +1,116 @@
+package shuntingyard
+
+import "context"
+import "testing"
+
+/* value element returning two values */
+type pair_elt struct {}
+
+func (p *pair_elt)Precedence()(int) { return 0 }
+func (p *pair_elt)Associativity()(int) { return 0 }
+func (p *pair_elt)Kind()(int) { return Kind_value }
+func (p *pair_elt)String()(string) { return "pair" }
+func (p *pair_elt)Input_types()([][]Type) { return nil }
+func (p *pair_elt)Output_types()([][]Type) {
+	return [][]Type{[]Type{Type_of[int64]()}, []Type{Type_of[string]()}}
+}
+func (p *pair_elt)Execute(ctx context.Context, in []Value)([]Value, error) {
+	return []Value{Value_of(int64(3)), Value_of("a")}, nil
+}
+
+/* push the elements, finalize the expression, check its output types
+ * and execute it. return the values, nil on error.
+ */
+func push_execute(t *testing.T, elts []Elt, typ string)(*Expr, []Value) {
+	var e *Expr
+	var elt Elt
+	var v []Value
+	var err error
+
+	e = New(nil)
+	for _, elt = range elts {
+		err = e.Push(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	err = e.Finalize()
+	if err != nil {
+		t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+		return e, nil
+	}
+	if Type_list(e.Output_types()) != typ {
+		t.Errorf("Expect %s for %q, got %s", typ, e.String(), Type_list(e.Output_types()))
+	}
+	v, err = e.Execute(With_checked(context.Background()), nil)
+	if err != nil {
+		t.Errorf("Unexpected error for %q: %s", e.String(), err.Error())
+		return e, nil
+	}
+	return e, v
+}
+
+/* push the elements and check that Finalize rejects them */
+func push_reject(t *testing.T, elts []Elt) {
+	var e *Expr
+	var elt Elt
+	var err error
+
+	e = New(nil)
+	for _, elt = range elts {
+		err = e.Push(elt)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+	err = e.Finalize()
+	if err == nil {
+		t.Errorf("Expect error for %q, got no error", e.String())
+	}
+}
+
+func Test_tuple(t *testing.T) {
+	var pair Elt
+	var add Elt
+	var one Elt
+	var e *Expr
+	var v []Value
+	var c struct {
+		elts []Elt
+		expect string
+		typ string
+	}
+
+	pair = &pair_elt{}
+	add = Binary("+", 1, Associativity_left, func(a int64, b int64)(int64, error) { return a + b, nil })
+	one = Constant("1", int64(1))
+
+	for _, c = range []struct {
+		elts []Elt
+		expect string
+		typ string
+	}{
+		{[]Elt{pair, Pack(2)}, "(3, a)", "tuple<int64, string>"},
+		{[]Elt{pair, Pack(2, "n", "s")}, "(n: 3, s: a)", "tuple<n: int64, s: string>"},
+		{[]Elt{pair, Pack(2), Project(0), one, add}, "4", "int64"},
+		{[]Elt{pair, Pack(2, "n", "s"), Project_name("s")}, "a", "string"},
+		{[]Elt{pair, Pack(2), Unpack(2)}, "a", "int64, string"},
+		{[]Elt{one, pair, Pack(3), Project(2)}, "a", "string"},
+	} {
+		e, v = push_execute(t, c.elts, c.typ)
+		if v != nil && v[len(v) - 1].Descr() != c.expect {
+			t.Errorf("Expect %s for %q, got %s", c.expect, e.String(), v[len(v) - 1].Descr())
+		}
+	}
+
+	/* the projections and the destructuring need the fields */
+	for _, c.elts = range [][]Elt{
+		{pair, Pack(2), Project(2)},
+		{pair, Pack(2), Project_name("n")},
+		{pair, Pack(2), Project(1), one, add},
+		{one, Project(0)},
+		{pair, Pack(2), Unpack(3)},
+	} {
+		push_reject(t, c.elts)
+	}
+}
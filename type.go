@@ -8,6 +8,79 @@ type Type interface {
 	Name()(string)
 }
 
+// Optional interface implemented by the types which can be used where
+// another type is required, typically "int" where "number" is required.
+type Assignable interface {
+	Assignable_to(t Type)(bool)
+}
+
+// Optional interface implemented by the types which accept values of
+// other types, typically "number" accepting "int".
+type Acceptor interface {
+	Accepts(t Type)(bool)
+}
+
+// Return true if the type provide can be used where the type require is
+// required. The types are compatible if they are equal, or if provide
+// implements Assignable, or if require implements Acceptor.
+func Is_assignable(provide Type, require Type)(bool) {
+	var a Assignable
+	var ac Acceptor
+	var ok bool
+
+	if provide == require {
+		return true
+	}
+	a, ok = provide.(Assignable)
+	if ok && a.Assignable_to(require) {
+		return true
+	}
+	ac, ok = require.(Acceptor)
+	if ok && ac.Accepts(provide) {
+		return true
+	}
+	return false
+}
+
+// Optional interface implemented by the types which represent the
+// absence of value, like nil. The alternatives containing a null type
+// are nullable.
+type Null_type interface {
+	Is_null()(bool)
+}
+
+// Return true if one of the alternatives is a null type
+func Is_nullable(types []Type)(bool) {
+	var t Type
+	var n Null_type
+	var ok bool
+
+	for _, t = range types {
+		n, ok = t.(Null_type)
+		if ok && n.Is_null() {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the alternatives without the null types
+func Strip_null(types []Type)([]Type) {
+	var out []Type
+	var t Type
+	var n Null_type
+	var ok bool
+
+	for _, t = range types {
+		n, ok = t.(Null_type)
+		if ok && n.Is_null() {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
 // Describe alternative of types
 func Type_desc(types []Type)(string) {
 	var t Type